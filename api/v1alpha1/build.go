@@ -2,7 +2,9 @@ package v1alpha1
 
 import (
 	"fmt"
+	"slices"
 
+	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/cakehappens/gocto"
 	"github.com/goccy/go-yaml"
 
@@ -27,6 +29,21 @@ func (s *Service) Inflate() {
 	}
 
 	for gIdx, group := range s.DestinationGroups {
+		if group.Verification != nil {
+			verification := *group.Verification
+			verification.HealthTimeoutSeconds = util.CoalescePointers(verification.HealthTimeoutSeconds, util.Ptr(300))
+			s.DestinationGroups[gIdx].Verification = &verification
+		}
+
+		if windows := util.CoalescePointers(group.DeployWindows, s.DeployWindows); windows != nil {
+			inflated := *windows
+			if inflated.Action == "" {
+				inflated.Action = DeployWindowActionFail
+			}
+			inflated.MaxWaitMinutes = util.CoalescePointers(inflated.MaxWaitMinutes, util.Ptr(60))
+			s.DestinationGroups[gIdx].DeployWindows = &inflated
+		}
+
 		for dIdx, dest := range group.Destinations {
 			dest.Namespace = util.CoalesceStrings(
 				dest.Namespace,
@@ -47,12 +64,80 @@ func (s *Service) Inflate() {
 				util.Ptr(30),
 			)
 
+			dest.ArgoCD.Source.RepoURL = util.CoalesceStrings(
+				dest.ArgoCD.Source.RepoURL,
+				group.ArgoCD.Source.RepoURL,
+				s.ArgoCD.Source.RepoURL,
+			)
+
+			dest.ArgoCD.Source.TargetRevision = util.CoalesceStrings(
+				dest.ArgoCD.Source.TargetRevision,
+				group.ArgoCD.Source.TargetRevision,
+				s.ArgoCD.Source.TargetRevision,
+			)
+
+			dest.ArgoCD.Source.Path = util.CoalesceStrings(
+				dest.ArgoCD.Source.Path,
+				group.ArgoCD.Source.Path,
+				s.ArgoCD.Source.Path,
+			)
+
+			dest.ArgoCD.Source.Include = util.CoalesceStrings(
+				dest.ArgoCD.Source.Include,
+				group.ArgoCD.Source.Include,
+				s.ArgoCD.Source.Include,
+			)
+
+			dest.ArgoCD.Source.Exclude = util.CoalesceStrings(
+				dest.ArgoCD.Source.Exclude,
+				group.ArgoCD.Source.Exclude,
+				s.ArgoCD.Source.Exclude,
+			)
+
+			if dest.ArgoCD.Source.Jsonnet.IsZero() {
+				if !group.ArgoCD.Source.Jsonnet.IsZero() {
+					dest.ArgoCD.Source.Jsonnet = group.ArgoCD.Source.Jsonnet
+				} else {
+					dest.ArgoCD.Source.Jsonnet = s.ArgoCD.Source.Jsonnet
+				}
+			}
+
+			dest.ArgoCD.Source.Helm = layerHelmSources(
+				s.ArgoCD.Source.Helm,
+				group.ArgoCD.Source.Helm,
+				dest.ArgoCD.Source.Helm,
+			)
+
+			dest.ArgoCD.Source.Kustomize = layerKustomizeSources(
+				s.ArgoCD.Source.Kustomize,
+				group.ArgoCD.Source.Kustomize,
+				dest.ArgoCD.Source.Kustomize,
+			)
+
+			dest.ArgoCD.Sources = util.CoalesceSlices(
+				dest.ArgoCD.Sources,
+				group.ArgoCD.Sources,
+				s.ArgoCD.Sources,
+			)
+
 			dest.ArgoCD.ApplicationFilePath = util.CoalesceStrings(
 				dest.ArgoCD.ApplicationFilePath,
 				group.ArgoCD.ApplicationFilePath,
 				s.ArgoCD.ApplicationFilePath,
 			)
 
+			dest.ArgoCD.Server = util.CoalescePointers(
+				dest.ArgoCD.Server,
+				group.ArgoCD.Server,
+				s.ArgoCD.Server,
+			)
+
+			dest.ArgoCD.Rollback = util.CoalescePointers(
+				dest.ArgoCD.Rollback,
+				group.ArgoCD.Rollback,
+				s.ArgoCD.Rollback,
+			)
+
 			dest.ArgoCD.SyncRetryLimit = util.CoalescePointers(
 				dest.ArgoCD.SyncRetryLimit,
 				group.ArgoCD.SyncRetryLimit,
@@ -69,6 +154,51 @@ func (s *Service) Inflate() {
 				},
 			)
 
+			dest.Github.PushRetryLimit = util.CoalescePointers(
+				dest.Github.PushRetryLimit,
+				group.Github.PushRetryLimit,
+				s.Github.PushRetryLimit,
+				util.Ptr(5),
+			)
+
+			// copied, as the coalesced promotion may be shared by other destinations
+			promotion := *util.CoalescePointers(
+				dest.Github.Promotion,
+				group.Github.Promotion,
+				s.Github.Promotion,
+				&Promotion{},
+			)
+			promotion.Strategy = PromotionStrategy(util.CoalesceStrings(string(promotion.Strategy), string(PromotionStrategyCommit)))
+			promotion.MergeMethod = util.CoalesceStrings(promotion.MergeMethod, "squash")
+			if promotion.MergeTimeoutMinutes == 0 {
+				promotion.MergeTimeoutMinutes = 30
+			}
+			dest.Github.Promotion = &promotion
+
+			dest.Github.Tools.ArgoCD = util.CoalescePointers(
+				dest.Github.Tools.ArgoCD,
+				group.Github.Tools.ArgoCD,
+				s.Github.Tools.ArgoCD,
+			)
+
+			dest.Github.Tools.Yq = util.CoalescePointers(
+				dest.Github.Tools.Yq,
+				group.Github.Tools.Yq,
+				s.Github.Tools.Yq,
+			)
+
+			dest.Github.Container = util.CoalescePointers(
+				dest.Github.Container,
+				group.Github.Container,
+				s.Github.Container,
+			)
+
+			dest.Github.Environment = util.CoalescePointers(
+				dest.Github.Environment,
+				group.Github.Environment,
+				s.Github.Environment,
+			)
+
 			dest.Github.PreDeploySteps = util.CoalesceSlices(
 				dest.Github.PreDeploySteps,
 				group.Github.PreDeploySteps,
@@ -97,3 +227,78 @@ func (s *Service) Inflate() {
 		}
 	}
 }
+
+// layerHelmSources combines helm sources from least to most specific.
+// Scalar fields are overridden, value files are appended, values are deep merged,
+// and parameters are merged by name.
+func layerHelmSources(sources ...*HelmSource) *HelmSource {
+	var result *HelmSource
+
+	for _, src := range sources {
+		if src == nil {
+			continue
+		}
+
+		if result == nil {
+			result = &HelmSource{}
+		}
+
+		result.RepoURL = util.CoalesceStrings(src.RepoURL, result.RepoURL)
+		result.Chart = util.CoalesceStrings(src.Chart, result.Chart)
+		result.Version = util.CoalesceStrings(src.Version, result.Version)
+		result.ReleaseName = util.CoalesceStrings(src.ReleaseName, result.ReleaseName)
+		result.ValueFiles = append(slices.Clone(result.ValueFiles), src.ValueFiles...)
+		result.Values = util.MergeMapsDeep(result.Values, src.Values)
+
+		for _, param := range src.Parameters {
+			idx := slices.IndexFunc(result.Parameters, func(p argov1alpha1.HelmParameter) bool {
+				return p.Name == param.Name
+			})
+			if idx >= 0 {
+				result.Parameters[idx] = param
+			} else {
+				result.Parameters = append(result.Parameters, param)
+			}
+		}
+	}
+
+	return result
+}
+
+// layerKustomizeSources combines kustomize sources from least to most specific.
+// Scalar fields are overridden, images are merged by image name, labels are merged,
+// and patches and components are appended.
+func layerKustomizeSources(sources ...*KustomizeSource) *KustomizeSource {
+	var result *KustomizeSource
+
+	for _, src := range sources {
+		if src == nil {
+			continue
+		}
+
+		if result == nil {
+			result = &KustomizeSource{}
+		}
+
+		result.NamePrefix = util.CoalesceStrings(src.NamePrefix, result.NamePrefix)
+		result.NameSuffix = util.CoalesceStrings(src.NameSuffix, result.NameSuffix)
+		result.Patches = append(slices.Clone(result.Patches), src.Patches...)
+		result.Components = append(slices.Clone(result.Components), src.Components...)
+
+		if len(src.CommonLabels) > 0 {
+			result.CommonLabels = util.MergeMapsShallow(result.CommonLabels, src.CommonLabels)
+		}
+
+		images := slices.Clone(result.Images)
+		for _, image := range src.Images {
+			if idx := images.Find(image); idx >= 0 {
+				images[idx] = image
+			} else {
+				images = append(images, image)
+			}
+		}
+		result.Images = images
+	}
+
+	return result
+}
@@ -3,9 +3,12 @@ package v1alpha1
 import (
 	"fmt"
 
+	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/cakehappens/gocto"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
 )
 
 var _ = Describe("Service.Inflate()", func() {
@@ -69,4 +72,210 @@ var _ = Describe("Service.Inflate()", func() {
 			})
 		}
 	})
+
+	Context("destination.argoCD.source.path", func() {
+		BeforeEach(func() {
+			service.DestinationGroups = DestinationGroups{
+				{
+					Destinations: Destinations{
+						{},
+					},
+				},
+			}
+		})
+
+		type TableEntry struct {
+			serviceLevel string
+			groupLevel   string
+			destLevel    string
+
+			expected string
+		}
+
+		for _, entry := range []TableEntry{
+			{"top", "", "", "top"},
+			{"", "group", "", "group"},
+			{"top", "group", "", "group"},
+			{"", "", "dest", "dest"},
+			{"top", "group", "dest", "dest"},
+		} {
+			Context("entry", func() {
+				BeforeEach(func() {
+					service.ArgoCD.Source.Path = entry.serviceLevel
+					service.DestinationGroups[0].ArgoCD.Source.Path = entry.groupLevel
+					service.DestinationGroups[0].Destinations[0].ArgoCD.Source.Path = entry.destLevel
+				})
+
+				It(fmt.Sprintf("should set source path to %s", entry.expected), func() {
+					Expect(service.DestinationGroups[0].Destinations[0].ArgoCD.Source.Path).To(Equal(entry.expected))
+				})
+			})
+		}
+	})
+
+	Context("destination.argoCD.source.helm", func() {
+		BeforeEach(func() {
+			service.ArgoCD.Source.Helm = &HelmSource{
+				RepoURL:    "https://charts.example.com",
+				Chart:      "podinfo",
+				Version:    "1.0.0",
+				ValueFiles: []string{"values.yaml"},
+				Values: map[string]any{
+					"replicas": 1,
+					"image": map[string]any{
+						"repository": "podinfo",
+						"tag":        "latest",
+					},
+				},
+				Parameters: []argov1alpha1.HelmParameter{
+					{Name: "a", Value: "service"},
+					{Name: "b", Value: "service"},
+				},
+			}
+			service.DestinationGroups = DestinationGroups{
+				{
+					ArgoCD: ArgoCD{
+						Source: Source{
+							Helm: &HelmSource{
+								Version:    "1.1.0",
+								ValueFiles: []string{"values-prod.yaml"},
+								Values: map[string]any{
+									"image": map[string]any{
+										"tag": "stable",
+									},
+								},
+							},
+						},
+					},
+					Destinations: Destinations{
+						{
+							ArgoCD: ArgoCD{
+								Source: Source{
+									Helm: &HelmSource{
+										Values: map[string]any{
+											"replicas": 3,
+										},
+										Parameters: []argov1alpha1.HelmParameter{
+											{Name: "b", Value: "destination"},
+										},
+									},
+								},
+							},
+						},
+						{},
+					},
+				},
+			}
+		})
+
+		It("should layer service, group & destination helm sources", func() {
+			Expect(service.DestinationGroups[0].Destinations[0].ArgoCD.Source.Helm).To(Equal(&HelmSource{
+				RepoURL:    "https://charts.example.com",
+				Chart:      "podinfo",
+				Version:    "1.1.0",
+				ValueFiles: []string{"values.yaml", "values-prod.yaml"},
+				Values: map[string]any{
+					"replicas": 3,
+					"image": map[string]any{
+						"repository": "podinfo",
+						"tag":        "stable",
+					},
+				},
+				Parameters: []argov1alpha1.HelmParameter{
+					{Name: "a", Value: "service"},
+					{Name: "b", Value: "destination"},
+				},
+			}))
+		})
+
+		It("should not leak destination values into sibling destinations", func() {
+			helm := service.DestinationGroups[0].Destinations[1].ArgoCD.Source.Helm
+			Expect(helm.Values).To(HaveKeyWithValue("replicas", 1))
+			Expect(helm.Parameters).To(ContainElement(argov1alpha1.HelmParameter{Name: "b", Value: "service"}))
+		})
+	})
+
+	Context("destination.argoCD.source.kustomize", func() {
+		BeforeEach(func() {
+			service.ArgoCD.Source = Source{
+				Path: "base",
+				Kustomize: &KustomizeSource{
+					NamePrefix:   "podinfo-",
+					Images:       argov1alpha1.KustomizeImages{"podinfo:latest", "redis:7"},
+					CommonLabels: map[string]string{"team": "web"},
+				},
+			}
+			service.DestinationGroups = DestinationGroups{
+				{
+					ArgoCD: ArgoCD{
+						Source: Source{
+							Path: "overlays/prod",
+							Kustomize: &KustomizeSource{
+								Images:       argov1alpha1.KustomizeImages{"podinfo:6.9.2"},
+								CommonLabels: map[string]string{"env": "prod"},
+								Components:   []string{"../../components/hpa"},
+							},
+						},
+					},
+					Destinations: Destinations{
+						{
+							ArgoCD: ArgoCD{
+								Source: Source{
+									Path: "overlays/prod-eu",
+								},
+							},
+						},
+					},
+				},
+			}
+		})
+
+		It("should select the most specific overlay path", func() {
+			Expect(service.DestinationGroups[0].Destinations[0].ArgoCD.Source.Path).To(Equal("overlays/prod-eu"))
+		})
+
+		It("should layer service & group kustomize sources", func() {
+			Expect(service.DestinationGroups[0].Destinations[0].ArgoCD.Source.Kustomize).To(Equal(&KustomizeSource{
+				NamePrefix:   "podinfo-",
+				Images:       argov1alpha1.KustomizeImages{"podinfo:6.9.2", "redis:7"},
+				CommonLabels: map[string]string{"team": "web", "env": "prod"},
+				Components:   []string{"../../components/hpa"},
+			}))
+		})
+	})
+
+	Context("destinationGroup.deployWindows", func() {
+		BeforeEach(func() {
+			service.DeployWindows = &DeployWindows{
+				Blackouts: []TimeWindow{{Cron: "0 0 24 12 *", Duration: "48h"}},
+			}
+			service.DestinationGroups = DestinationGroups{
+				{Name: "staging"},
+				{
+					Name: "prod",
+					DeployWindows: &DeployWindows{
+						Allowed: []TimeWindow{{Cron: "0 22 * * 1-5", Duration: "8h"}},
+						Action:  DeployWindowActionSkip,
+					},
+				},
+			}
+		})
+
+		It("should inherit the deploy windows of the service, with defaults", func() {
+			Expect(service.DestinationGroups[0].DeployWindows).To(Equal(&DeployWindows{
+				Blackouts:      []TimeWindow{{Cron: "0 0 24 12 *", Duration: "48h"}},
+				Action:         DeployWindowActionFail,
+				MaxWaitMinutes: util.Ptr(60),
+			}))
+		})
+
+		It("should use the deploy windows of the group instead", func() {
+			Expect(service.DestinationGroups[1].DeployWindows.Blackouts).To(BeEmpty())
+			Expect(service.DestinationGroups[1].DeployWindows.Action).To(Equal(DeployWindowActionSkip))
+		})
+
+		It("should not default the deploy windows of the service itself", func() {
+			Expect(service.DeployWindows.Action).To(BeEmpty())
+		})
+	})
 })
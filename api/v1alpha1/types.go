@@ -3,7 +3,13 @@ package v1alpha1
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
 	"github.com/cakehappens/gocto"
@@ -16,6 +22,16 @@ type Service struct {
 	ApplicationNameUniquenessStrategy ApplicationNameUniquenessStrategy `json:"applicationNameUniquenessStrategy,omitempty,omitzero"`
 	ArgoCD                            ArgoCD                            `json:"argoCD,omitempty,omitzero"`
 	Github                            Github                            `json:"github,omitempty,omitzero"`
+	// DependsOn lists the services (generated together with this one) which must be deployed first:
+	// each destination group waits for the destination group of the same name of those services.
+	// Services which are not generated together with this one are not waited for.
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// DeployWindows restricts when destinations may be promoted to, unless a group declares its own.
+	DeployWindows *DeployWindows `json:"deployWindows,omitempty,omitzero"`
+	// Schedule triggers the top-level workflow periodically, promoting the head of argoCD.source.commitBranch
+	// (or of the default branch), e.g. to batch promotions to prod off-hours.
+	// GitHub evaluates the cron expressions in UTC.
+	Schedule []Schedule `json:"schedule,omitempty"`
 
 	// For Testing
 	sourceValidatorFunc            func(source Source) error
@@ -23,12 +39,101 @@ type Service struct {
 }
 
 type ArgoCD struct {
-	ExtraArgs           []string                `json:"extraArgs,omitempty"`
-	Source              Source                  `json:"source,omitempty,omitzero"`
+	ExtraArgs []string `json:"extraArgs,omitempty"`
+	Source    Source   `json:"source,omitempty,omitzero"`
+	// Sources produces a multi-source application, and is mutually exclusive with Source (except for Source.CommitBranch).
+	// Unlike Source, the most specific list of sources is used as-is, rather than layered.
+	Sources             []Source                `json:"sources,omitempty"`
 	SyncPolicy          argov1alpha1.SyncPolicy `json:"syncPolicy,omitempty,omitzero"`
 	SyncTimeoutSeconds  *int                    `json:"syncTimeoutSeconds,omitempty,omitzero"`
 	SyncRetryLimit      *int                    `json:"syncRetryLimit,omitempty,omitzero"`
 	ApplicationFilePath string                  `json:"applicationFilePath,omitempty,omitzero"`
+	// Server is the Argo CD instance managing the destination, and how to authenticate with it.
+	// When unset, the argocd CLI must be configured by the preDeploySteps.
+	Server *ArgoCDServer `json:"server,omitempty,omitzero"`
+	// Rollback reverts the promotion of a destination which fails to sync or to be verified.
+	Rollback *RollbackPolicy `json:"rollback,omitempty,omitzero"`
+}
+
+// RollbackPolicy reverts the promotion commit of the application, and syncs the previous targetRevision.
+// The deploy job still fails, so dependent groups are not deployed.
+type RollbackPolicy struct {
+	// Enabled allows a group or destination to opt out of the rollback policy of the service.
+	Enabled bool `json:"enabled"`
+}
+
+func (r *RollbackPolicy) IsEnabled() bool {
+	return r != nil && r.Enabled
+}
+
+type ArgoCDServer struct {
+	// Address is the host (and optionally port) of the Argo CD API server.
+	Address   string     `json:"address"`
+	GRPCWeb   bool       `json:"grpcWeb,omitempty,omitzero"`
+	Insecure  bool       `json:"insecure,omitempty,omitzero"`
+	PlainText bool       `json:"plainText,omitempty,omitzero"`
+	Auth      ArgoCDAuth `json:"auth,omitempty,omitzero"`
+}
+
+// ArgoCDAuth selects at most one way to authenticate with Argo CD.
+type ArgoCDAuth struct {
+	// AuthTokenSecretName names the secret holding an Argo CD API token.
+	AuthTokenSecretName string `json:"authTokenSecretName,omitempty,omitzero"`
+	// UsernameSecretName & PasswordSecretName name the secrets holding the credentials of a local Argo CD account.
+	UsernameSecretName string `json:"usernameSecretName,omitempty,omitzero"`
+	PasswordSecretName string `json:"passwordSecretName,omitempty,omitzero"`
+	// OIDC exchanges the GitHub OIDC token of the job for an Argo CD (Dex) token.
+	OIDC *ArgoCDOIDCAuth `json:"oidc,omitempty,omitzero"`
+}
+
+// ArgoCDOIDCAuth exchanges a GitHub OIDC token using Dex's token exchange,
+// which requires a Dex OIDC connector trusting https://token.actions.githubusercontent.com.
+type ArgoCDOIDCAuth struct {
+	// ConnectorID is the id of the Dex connector trusting GitHub's OIDC tokens.
+	ConnectorID string `json:"connectorID"`
+	// Audience of the GitHub OIDC token, defaulting to the Address of the server.
+	Audience string `json:"audience,omitempty,omitzero"`
+	// ClientID of the Dex client the token is issued to, defaulting to argo-cd-cli.
+	ClientID string `json:"clientID,omitempty,omitzero"`
+}
+
+func (s *ArgoCDServer) Validate() error {
+	if s == nil {
+		return errors.New("server is nil")
+	}
+
+	var errs []error
+
+	if s.Address == "" {
+		errs = append(errs, errors.New("address is required"))
+	}
+
+	methods := 0
+	if s.Auth.AuthTokenSecretName != "" {
+		methods++
+	}
+
+	if s.Auth.UsernameSecretName != "" || s.Auth.PasswordSecretName != "" {
+		methods++
+
+		if s.Auth.UsernameSecretName == "" || s.Auth.PasswordSecretName == "" {
+			errs = append(errs, errors.New("usernameSecretName and passwordSecretName must be specified together"))
+		}
+	}
+
+	if s.Auth.OIDC != nil {
+		methods++
+
+		if s.Auth.OIDC.ConnectorID == "" {
+			errs = append(errs, errors.New("oidc connectorID is required"))
+		}
+	}
+
+	if methods > 1 {
+		errs = append(errs, errors.New("only one of authTokenSecretName, username/password or oidc may be specified"))
+	}
+
+	return errors.Join(errs...)
 }
 
 type Github struct {
@@ -38,6 +143,160 @@ type Github struct {
 	ExtraDeployJobs map[string]gocto.Job `json:"extraDeployJobs,omitempty"`
 	Secrets         *gocto.Secrets       `json:"secrets,omitempty"`
 	Env             map[string]string    `json:"env,omitempty"`
+	PushRetryLimit  *int                 `json:"pushRetryLimit,omitempty,omitzero"`
+	Promotion       *Promotion           `json:"promotion,omitempty,omitzero"`
+	// Tools pins the CLIs installed in the deploy jobs.
+	Tools Tools `json:"tools,omitempty,omitzero"`
+	// Container runs the deploy jobs in an image, which must provide the CLIs used by the deploy jobs,
+	// unless they are declared in Tools.
+	Container *gocto.Container `json:"container,omitempty,omitzero"`
+	// Environment is the GitHub environment of the deploy jobs, e.g. to require a review before deploying to production.
+	Environment *Environment `json:"environment,omitempty,omitzero"`
+}
+
+// Environment is a GitHub environment deployed to by the deploy job of a destination.
+type Environment struct {
+	Name string `json:"name"`
+	// URL is shown on the deployment, e.g. the address of the service in the destination.
+	URL string `json:"url,omitempty,omitzero"`
+	// Protection is the intended configuration of the environment.
+	// Environments are configured in the repository settings rather than by workflows,
+	// so the protection is reported by generate instead of being applied.
+	Protection EnvironmentProtection `json:"protection,omitempty,omitzero"`
+}
+
+type EnvironmentProtection struct {
+	// RequiredReviewers are the users or teams (org/team), one of which must approve each deployment.
+	RequiredReviewers []string `json:"requiredReviewers,omitempty"`
+	// PreventSelfReview prevents the user triggering a deployment from approving it.
+	PreventSelfReview bool `json:"preventSelfReview,omitempty,omitzero"`
+	// WaitTimerMinutes delays each deployment, after it is approved.
+	WaitTimerMinutes int `json:"waitTimerMinutes,omitempty,omitzero"`
+	// DeploymentBranches are the branch name patterns allowed to deploy to the environment.
+	DeploymentBranches []string `json:"deploymentBranches,omitempty"`
+}
+
+func (e *Environment) Validate() error {
+	if e == nil {
+		return errors.New("environment is nil")
+	}
+
+	var errs []error
+
+	if e.Name == "" {
+		errs = append(errs, errors.New("name is required"))
+	}
+
+	// limits of the GitHub environment protection rules
+	if e.Protection.WaitTimerMinutes < 0 || e.Protection.WaitTimerMinutes > 43200 {
+		errs = append(errs, errors.New("waitTimerMinutes must be between 0 and 43200"))
+	}
+
+	if len(e.Protection.RequiredReviewers) > 6 {
+		errs = append(errs, errors.New("at most 6 requiredReviewers may be specified"))
+	}
+
+	if e.Protection.PreventSelfReview && len(e.Protection.RequiredReviewers) == 0 {
+		errs = append(errs, errors.New("preventSelfReview requires requiredReviewers"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Tools declares the versions of the CLIs used by the deploy jobs.
+// Undeclared tools are expected to be present on the runner (argocd),
+// or built from a pinned release with the Go toolchain of the runner (yq).
+type Tools struct {
+	ArgoCD *Tool `json:"argoCD,omitempty,omitzero"`
+	Yq     *Tool `json:"yq,omitempty,omitzero"`
+}
+
+// Tool is a released version of a CLI, installed from the release binaries verified against their checksums.
+type Tool struct {
+	// Version is the release tag, e.g. v3.1.0.
+	Version string `json:"version"`
+	// SHA256 are the checksums of the linux binaries of the release, by architecture (amd64, arm64).
+	SHA256 map[string]string `json:"sha256"`
+}
+
+var sha256Pattern = regexp.MustCompile(`^[a-f0-9]{64}$`)
+
+func (t *Tool) Validate() error {
+	if t == nil {
+		return errors.New("tool is nil")
+	}
+
+	var errs []error
+
+	if t.Version == "" {
+		errs = append(errs, errors.New("version is required"))
+	}
+
+	if len(t.SHA256) == 0 {
+		errs = append(errs, errors.New("at least one sha256 checksum is required"))
+	}
+
+	for _, arch := range slices.Sorted(maps.Keys(t.SHA256)) {
+		if arch != "amd64" && arch != "arm64" {
+			errs = append(errs, fmt.Errorf("unsupported architecture: %q", arch))
+		}
+
+		if !sha256Pattern.MatchString(t.SHA256[arch]) {
+			errs = append(errs, fmt.Errorf("invalid sha256 checksum for %s: %q", arch, t.SHA256[arch]))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+type PromotionStrategy string
+
+const (
+	// PromotionStrategyCommit pushes the promotion commit directly to the commit branch.
+	PromotionStrategyCommit PromotionStrategy = "commit"
+	// PromotionStrategyPullRequest opens (or updates) a pull request with the promotion commit,
+	// and only deploys once it has been merged.
+	PromotionStrategyPullRequest PromotionStrategy = "pullRequest"
+)
+
+// Promotion configures how the promotion commit reaches the commit branch.
+type Promotion struct {
+	Strategy PromotionStrategy `json:"strategy,omitempty,omitzero"`
+	// AutoMerge enables auto-merge on the pull request, so it merges as soon as its requirements are met.
+	AutoMerge bool `json:"autoMerge,omitempty,omitzero"`
+	// MergeMethod is one of merge, squash or rebase.
+	MergeMethod string `json:"mergeMethod,omitempty,omitzero"`
+	// MergeTimeoutMinutes bounds how long the deploy job waits for the pull request to be merged.
+	MergeTimeoutMinutes int `json:"mergeTimeoutMinutes,omitempty,omitzero"`
+	// TokenSecretName names the secret holding the token used to open the pull request, defaulting to the GITHUB_TOKEN.
+	// Note that pull requests opened using the GITHUB_TOKEN do not trigger workflows, such as required checks.
+	TokenSecretName string `json:"tokenSecretName,omitempty,omitzero"`
+}
+
+func (p *Promotion) Validate() error {
+	if p == nil {
+		return errors.New("promotion is nil")
+	}
+
+	var errs []error
+
+	switch p.Strategy {
+	case PromotionStrategyCommit, PromotionStrategyPullRequest:
+	default:
+		errs = append(errs, fmt.Errorf("unknown promotion strategy: %q", p.Strategy))
+	}
+
+	switch p.MergeMethod {
+	case "merge", "squash", "rebase":
+	default:
+		errs = append(errs, fmt.Errorf("unknown merge method: %q", p.MergeMethod))
+	}
+
+	if p.MergeTimeoutMinutes <= 0 {
+		errs = append(errs, errors.New("mergeTimeoutMinutes must be positive"))
+	}
+
+	return errors.Join(errs...)
 }
 
 type ApplicationNameUniquenessStrategy struct {
@@ -74,11 +333,100 @@ func (s *Service) Validate() error {
 
 	errs = append(errs, s.destinationGroupsValidatorFunc(s.DestinationGroups))
 
+	// sources may be overridden per group/destination, so completeness is only checked
+	// on the effective (inflated) source of each destination
+	for _, group := range s.DestinationGroups {
+		for _, dest := range group.Destinations {
+			if err := dest.ArgoCD.ValidateEffective(); err != nil {
+				errs = append(errs, fmt.Errorf("destination group: %s: destination: %s: %w",
+					group.Name, CoalesceSanitizeDestination(dest), err))
+			}
+		}
+	}
+
+	errs = append(errs, s.validateEnvironmentProtections())
+	errs = append(errs, s.validateDeployWindowSkips())
+
+	for _, schedule := range s.Schedule {
+		if err := validateCron(schedule.Cron); err != nil {
+			errs = append(errs, fmt.Errorf("validating schedule: %q: %w", schedule.Cron, err))
+		}
+	}
+
+	for idx, dependency := range s.DependsOn {
+		switch {
+		case dependency == s.Name:
+			errs = append(errs, errors.New("service cannot depend on itself"))
+		case slices.Contains(s.DependsOn[:idx], dependency):
+			errs = append(errs, fmt.Errorf("duplicate service dependency: %s", dependency))
+		}
+	}
+
 	return errors.Join(errs...)
 }
 
 type DestinationGroups []DestinationGroup
 
+// validateDeployWindowSkips ensures only the groups no other group waits for skip their promotions outside of the
+// deploy windows, as the groups waiting for them would otherwise deploy a revision the skipped destinations never got.
+func (s *Service) validateDeployWindowSkips() error {
+	needs := s.DestinationGroups.Needs()
+
+	var errs []error
+	for _, group := range s.DestinationGroups {
+		if group.DeployWindows == nil || group.DeployWindows.Action != DeployWindowActionSkip {
+			continue
+		}
+
+		for _, other := range s.DestinationGroups {
+			if slices.Contains(needs[other.Name], group.Name) {
+				errs = append(errs, fmt.Errorf("destination group: %s: deployWindows: group %s waits for it, so it must wait or fail, rather than skip",
+					group.Name, other.Name))
+				break
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateEnvironmentProtections ensures destinations sharing an environment agree on its protection,
+// as an environment only has a single configuration.
+func (s *Service) validateEnvironmentProtections() error {
+	type declaration struct {
+		protection EnvironmentProtection
+		declaredBy string
+	}
+
+	declarations := make(map[string]declaration)
+
+	var errs []error
+
+	for _, group := range s.DestinationGroups {
+		for _, dest := range group.Destinations {
+			env := dest.Github.Environment
+			if env == nil || reflect.ValueOf(env.Protection).IsZero() {
+				continue
+			}
+
+			declaredBy := fmt.Sprintf("destination group: %s: destination: %s", group.Name, CoalesceSanitizeDestination(dest))
+
+			existing, ok := declarations[env.Name]
+			if !ok {
+				declarations[env.Name] = declaration{protection: env.Protection, declaredBy: declaredBy}
+				continue
+			}
+
+			if !reflect.DeepEqual(existing.protection, env.Protection) {
+				errs = append(errs, fmt.Errorf("environment %q: protection differs between %s and %s",
+					env.Name, existing.declaredBy, declaredBy))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 func (dg DestinationGroups) Validate() error {
 	var errs []error
 
@@ -107,15 +455,146 @@ func (dg DestinationGroups) Validate() error {
 
 	}
 
+	for _, destinationGroup := range dg {
+		for _, dependency := range destinationGroup.DependsOn {
+			if _, ok := groupsFound[dependency]; !ok {
+				errs = append(errs, fmt.Errorf("destination group: %s: depends on unknown destination group: %s", destinationGroup.Name, dependency))
+			}
+		}
+	}
+
+	// cycles can only be reliably detected once group names are known to be unique
+	if len(groupsFound) != len(dg) {
+		return errors.Join(errs...)
+	}
+
+	if cycle := dg.findCycle(); len(cycle) > 0 {
+		errs = append(errs, fmt.Errorf("destination group dependency cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
 	return errors.Join(errs...)
 }
 
+// Needs returns the names of the groups each group must wait for.
+// A group waits for the groups it declares in dependsOn, or else for the group listed before it.
+func (dg DestinationGroups) Needs() map[string][]string {
+	needs := make(map[string][]string, len(dg))
+
+	for idx, group := range dg {
+		switch {
+		case len(group.DependsOn) > 0:
+			needs[group.Name] = group.DependsOn
+		case idx > 0:
+			needs[group.Name] = []string{dg[idx-1].Name}
+		default:
+			needs[group.Name] = nil
+		}
+	}
+
+	return needs
+}
+
+// FinalGroups returns the names of the groups no other group waits for, i.e. the last stage(s) of a rollout, in order.
+func (dg DestinationGroups) FinalGroups() []string {
+	needs := dg.Needs()
+
+	var final []string
+	for _, group := range dg {
+		needed := slices.ContainsFunc(dg, func(other DestinationGroup) bool {
+			return slices.Contains(needs[other.Name], group.Name)
+		})
+		if !needed {
+			final = append(final, group.Name)
+		}
+	}
+
+	return final
+}
+
+// findCycle returns the path of the first dependency cycle found, if any.
+func (dg DestinationGroups) findCycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	needs := dg.Needs()
+	state := make(map[string]int, len(dg))
+
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			start := slices.Index(path, name)
+			return append(slices.Clone(path[start:]), name)
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dependency := range needs[name] {
+			if cycle := visit(dependency); cycle != nil {
+				return cycle
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, group := range dg {
+		if cycle := visit(group.Name); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
 type Source struct {
+	// RepoURL defaults to the service repository, whose revision is promoted.
+	RepoURL string `json:"repoURL,omitempty,omitzero"`
+	// TargetRevision is only used by sources that are not promoted, i.e. those from other git repositories.
+	TargetRevision string `json:"targetRevision,omitempty,omitzero"`
+	// Ref names the source, so that other sources of a multi-source application can refer to its files.
+	Ref          string                                `json:"ref,omitempty,omitzero"`
 	Path         string                                `json:"path,omitempty,omitzero"`
 	CommitBranch string                                `json:"commitBranch,omitempty,omitzero"`
 	Include      string                                `json:"include,omitempty,omitzero"`
 	Exclude      string                                `json:"exclude,omitempty,omitzero"`
 	Jsonnet      argov1alpha1.ApplicationSourceJsonnet `json:"jsonnet,omitempty,omitzero"`
+	Helm         *HelmSource                           `json:"helm,omitempty,omitzero"`
+	Kustomize    *KustomizeSource                      `json:"kustomize,omitempty,omitzero"`
+}
+
+// HelmSource renders the source as a Helm chart.
+// When RepoURL is empty, the chart is read from Path in the service repository,
+// otherwise Chart at Version is pulled from the Helm chart repository at RepoURL.
+type HelmSource struct {
+	RepoURL     string                       `json:"repoURL,omitempty,omitzero"`
+	Chart       string                       `json:"chart,omitempty,omitzero"`
+	Version     string                       `json:"version,omitempty,omitzero"`
+	ReleaseName string                       `json:"releaseName,omitempty,omitzero"`
+	ValueFiles  []string                     `json:"valueFiles,omitempty"`
+	Values      map[string]any               `json:"values,omitempty"`
+	Parameters  []argov1alpha1.HelmParameter `json:"parameters,omitempty"`
+}
+
+// KustomizeSource renders the kustomization found at Path in the service repository.
+// Groups and destinations typically select their own overlay by overriding Path.
+type KustomizeSource struct {
+	NamePrefix   string                        `json:"namePrefix,omitempty,omitzero"`
+	NameSuffix   string                        `json:"nameSuffix,omitempty,omitzero"`
+	Images       argov1alpha1.KustomizeImages  `json:"images,omitempty"`
+	CommonLabels map[string]string             `json:"commonLabels,omitempty"`
+	Patches      argov1alpha1.KustomizePatches `json:"patches,omitempty"`
+	Components   []string                      `json:"components,omitempty"`
 }
 
 func (s *Source) Validate() error {
@@ -126,17 +605,409 @@ func (s *Source) Validate() error {
 	return nil
 }
 
+// IsHelmChartRepository is true when the source is a chart pulled from a Helm chart repository,
+// in which case the promoted revision is the chart version, rather than a commit of the service repository.
+func (s *Source) IsHelmChartRepository() bool {
+	return s.Helm != nil && s.Helm.RepoURL != ""
+}
+
+// TracksServiceRepository is true when the source is read from the service repository itself.
+func (s *Source) TracksServiceRepository() bool {
+	return s.RepoURL == "" && !s.IsHelmChartRepository()
+}
+
+// IsPromoted is true when the deploy job writes the targetRevision of the source.
+func (s *Source) IsPromoted() bool {
+	return s.TracksServiceRepository() || s.IsHelmChartRepository()
+}
+
+// IsMultiSource is true when the application is rendered with spec.sources rather than spec.source.
+func (a *ArgoCD) IsMultiSource() bool {
+	return len(a.Sources) > 0
+}
+
+// EffectiveSources returns the sources of the application, regardless of whether it is multi-source.
+func (a *ArgoCD) EffectiveSources() []Source {
+	if a.IsMultiSource() {
+		return a.Sources
+	}
+
+	return []Source{a.Source}
+}
+
+// ValidateEffective validates the fully inflated source(s) of a destination.
+func (a *ArgoCD) ValidateEffective() error {
+	if !a.IsMultiSource() {
+		err := a.Source.ValidateEffective()
+		if err == nil && !a.Source.IsPromoted() {
+			err = errors.New("source must track the service repository or a helm repository")
+		}
+
+		return err
+	}
+
+	var errs []error
+
+	if a.Source.Path != "" || a.Source.Helm != nil || a.Source.Kustomize != nil {
+		errs = append(errs, errors.New("only one of source or sources may be specified"))
+	}
+
+	promoted := 0
+	for idx, src := range a.Sources {
+		if err := src.ValidateEffective(); err != nil {
+			errs = append(errs, fmt.Errorf("sources[%d]: %w", idx, err))
+		}
+
+		if src.IsPromoted() {
+			promoted++
+		}
+	}
+
+	if promoted == 0 {
+		errs = append(errs, errors.New("at least one source must track the service repository or a helm repository"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// ValidateEffective validates a fully inflated source.
+func (s *Source) ValidateEffective() error {
+	var errs []error
+
+	if s.Helm != nil && s.Kustomize != nil {
+		errs = append(errs, errors.New("only one of helm or kustomize may be specified"))
+	}
+
+	if s.IsHelmChartRepository() {
+		if s.Helm.Chart == "" {
+			errs = append(errs, errors.New("helm chart is required when using a helm repository"))
+		}
+
+		if s.Helm.Version == "" {
+			errs = append(errs, errors.New("helm chart version is required when using a helm repository"))
+		}
+	} else if s.Path == "" && s.Ref == "" {
+		errs = append(errs, errors.New("source path is required"))
+	}
+
+	return errors.Join(errs...)
+}
+
 type DestinationGroup struct {
-	Name                 string        `json:"name"`
+	Name string `json:"name"`
+	// DependsOn lists the destination groups that must be deployed before this group.
+	// See DestinationGroups.Needs for how groups are ordered when it is not set.
+	DependsOn            []string      `json:"dependsOn,omitempty"`
 	Destinations         []Destination `json:"destinations"`
 	DestinationNamespace string        `json:"destinationNamespace,omitempty,omitzero"`
 	ArgoCD               ArgoCD        `json:"argoCD,omitempty,omitzero"`
 	Github               Github        `json:"github,omitempty,omitzero"`
+	// Verification must pass for every destination of the group before dependent groups are deployed.
+	Verification *Verification `json:"verification,omitempty,omitzero"`
+	// RolloutStrategy deploys the destinations of the group in batches, rather than all at once.
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty,omitzero"`
+	// DeployWindows restricts when the destinations of the group may be promoted to.
+	DeployWindows *DeployWindows `json:"deployWindows,omitempty,omitzero"`
 
 	// For Testing
 	destinationsValidatorFunc func(destinations Destinations) error
 }
 
+// Verification is run by the deploy job of each destination, after syncing.
+type Verification struct {
+	// HealthTimeoutSeconds bounds how long to wait for the application to become healthy, defaulting to 300.
+	HealthTimeoutSeconds *int `json:"healthTimeoutSeconds,omitempty,omitzero"`
+	// SoakSeconds is how long the application must then remain healthy (or progressing, e.g. while scaling).
+	SoakSeconds int `json:"soakSeconds,omitempty,omitzero"`
+	// Checks are run once the application is healthy and has soaked, e.g. smoke tests.
+	Checks []gocto.Step `json:"checks,omitempty"`
+}
+
+func (v *Verification) Validate() error {
+	if v == nil {
+		return errors.New("verification is nil")
+	}
+
+	var errs []error
+
+	if v.HealthTimeoutSeconds != nil && *v.HealthTimeoutSeconds <= 0 {
+		errs = append(errs, errors.New("healthTimeoutSeconds must be positive"))
+	}
+
+	if v.SoakSeconds < 0 {
+		errs = append(errs, errors.New("soakSeconds must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// RolloutStrategy deploys the destinations of a group in successive batches.
+// A batch only starts once the previous one has finished, and the remaining batches are halted
+// once more than MaxFailures destinations have failed.
+type RolloutStrategy struct {
+	// Canary names a destination deployed on its own, before any other destination of the group.
+	// The remaining batches are halted when the canary fails, regardless of MaxFailures.
+	Canary string `json:"canary,omitempty"`
+	// Batches are the sizes of the successive batches, either a number of destinations, e.g. "2",
+	// or a percentage of the destinations of the group, rounded up, e.g. "25%".
+	// Destinations left once every batch has been deployed are deployed in batches of the last size.
+	Batches []string `json:"batches,omitempty"`
+	// MaxParallel bounds how many destinations are deployed at once, splitting larger batches.
+	MaxParallel int `json:"maxParallel,omitempty,omitzero"`
+	// MaxFailures is how many destinations may fail before the remaining batches are halted, defaulting to 0.
+	MaxFailures int `json:"maxFailures,omitempty,omitzero"`
+}
+
+func (r *RolloutStrategy) Validate() error {
+	if r == nil {
+		return errors.New("rolloutStrategy is nil")
+	}
+
+	var errs []error
+
+	for _, batch := range r.Batches {
+		if _, _, err := parseBatchSize(batch); err != nil {
+			errs = append(errs, fmt.Errorf("batch: %q: %w", batch, err))
+		}
+	}
+
+	if r.MaxParallel < 0 {
+		errs = append(errs, errors.New("maxParallel must not be negative"))
+	}
+
+	if r.MaxFailures < 0 {
+		errs = append(errs, errors.New("maxFailures must not be negative"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// parseBatchSize parses a batch size, either a number of destinations or a percentage.
+func parseBatchSize(batch string) (size int, percent bool, err error) {
+	value, percent := strings.CutSuffix(batch, "%")
+
+	size, err = strconv.Atoi(value)
+	if err != nil {
+		return 0, false, errors.New("must be a number or a percentage")
+	}
+
+	if size <= 0 {
+		return 0, false, errors.New("must be positive")
+	}
+
+	if percent && size > 100 {
+		return 0, false, errors.New("percentage must not exceed 100%")
+	}
+
+	return size, percent, nil
+}
+
+// Batches splits the destinations of the group into the batches they are deployed in, in order.
+// Without a rollout strategy, every destination is deployed at once, in a single batch.
+func (dg *DestinationGroup) Batches() [][]Destination {
+	if dg.RolloutStrategy == nil {
+		return [][]Destination{dg.Destinations}
+	}
+
+	var batches [][]Destination
+
+	remaining := dg.Destinations
+	if dg.RolloutStrategy.Canary != "" {
+		idx := slices.IndexFunc(remaining, func(dest Destination) bool {
+			return CoalesceSanitizeDestination(dest) == dg.RolloutStrategy.Canary
+		})
+		if idx >= 0 {
+			batches = append(batches, []Destination{remaining[idx]})
+			remaining = slices.Concat(remaining[:idx], remaining[idx+1:])
+		}
+	}
+
+	for idx := 0; len(remaining) > 0; idx++ {
+		size := len(remaining)
+		if len(dg.RolloutStrategy.Batches) > 0 {
+			var percent bool
+			size, percent, _ = parseBatchSize(dg.RolloutStrategy.Batches[min(idx, len(dg.RolloutStrategy.Batches)-1)])
+			if percent {
+				// rounded up, so that every batch deploys at least one destination
+				size = (len(dg.Destinations)*size + 99) / 100
+			}
+		}
+
+		if dg.RolloutStrategy.MaxParallel > 0 {
+			size = min(size, dg.RolloutStrategy.MaxParallel)
+		}
+
+		size = max(min(size, len(remaining)), 1)
+		batches = append(batches, remaining[:size])
+		remaining = remaining[size:]
+	}
+
+	return batches
+}
+
+// Schedule is a cron schedule triggering a workflow.
+type Schedule struct {
+	Cron string `json:"cron"`
+}
+
+// DeployWindows restricts when destinations may be promoted to: within any allowed window (if any is declared),
+// and outside of every blackout window. The deploy job of a destination checks the windows before promoting.
+// The rollback workflow bypasses the windows.
+type DeployWindows struct {
+	// Allowed are the only windows destinations may be promoted within, when any is declared.
+	Allowed []TimeWindow `json:"allowed,omitempty"`
+	// Blackouts are windows destinations must not be promoted within, even within an allowed window.
+	Blackouts []TimeWindow `json:"blackouts,omitempty"`
+	// Action is taken when a destination is promoted outside of the windows, defaulting to fail.
+	Action DeployWindowAction `json:"action,omitempty,omitzero"`
+	// MaxWaitMinutes bounds how long the wait action waits for a window to open, defaulting to 60.
+	MaxWaitMinutes *int `json:"maxWaitMinutes,omitempty,omitzero"`
+}
+
+type DeployWindowAction string
+
+const (
+	// DeployWindowActionWait waits for a window to open, and fails once MaxWaitMinutes have elapsed.
+	DeployWindowActionWait DeployWindowAction = "wait"
+	// DeployWindowActionSkip skips the promotion, without failing.
+	// It is only allowed on the groups no other group waits for, i.e. the last stage(s) of the rollout.
+	DeployWindowActionSkip DeployWindowAction = "skip"
+	// DeployWindowActionFail fails the deploy job, halting the rollout.
+	DeployWindowActionFail DeployWindowAction = "fail"
+)
+
+// maxWaitMinutesLimit is the longest a job may run on GitHub-hosted runners.
+const maxWaitMinutesLimit = 360
+
+// maxWindowDuration bounds windows, as the deploy job looks for the start of a window
+// as many days back as the window lasts, day by day.
+const maxWindowDuration = 7 * 24 * time.Hour
+
+// TimeWindow opens whenever the cron expression matches, and remains open for the duration.
+type TimeWindow struct {
+	// Cron is when the window opens, e.g. "0 22 * * 1-5". Only numbers, lists, ranges and steps are supported.
+	Cron string `json:"cron"`
+	// Duration is how long the window remains open, in minutes or hours, e.g. "8h", of at most a week.
+	Duration string `json:"duration"`
+	// TimeZone the cron expression is evaluated in, e.g. "Europe/Berlin", defaulting to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// DurationMinutes is how many minutes the window remains open.
+func (w TimeWindow) DurationMinutes() int {
+	duration, _ := time.ParseDuration(w.Duration)
+	return int(duration / time.Minute)
+}
+
+func (w *DeployWindows) Validate() error {
+	if w == nil {
+		return errors.New("deployWindows is nil")
+	}
+
+	var errs []error
+
+	if len(w.Allowed) == 0 && len(w.Blackouts) == 0 {
+		errs = append(errs, errors.New("at least one allowed or blackout window is required"))
+	}
+
+	for _, window := range w.Allowed {
+		if err := window.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("allowed window: %q: %w", window.Cron, err))
+		}
+	}
+
+	for _, window := range w.Blackouts {
+		if err := window.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("blackout window: %q: %w", window.Cron, err))
+		}
+	}
+
+	switch w.Action {
+	case DeployWindowActionWait, DeployWindowActionSkip, DeployWindowActionFail:
+	default:
+		errs = append(errs, fmt.Errorf("unsupported action: %q, must be one of: wait, skip, fail", w.Action))
+	}
+
+	if w.MaxWaitMinutes != nil && (*w.MaxWaitMinutes <= 0 || *w.MaxWaitMinutes > maxWaitMinutesLimit) {
+		errs = append(errs, fmt.Errorf("maxWaitMinutes must be between 1 and %d", maxWaitMinutesLimit))
+	}
+
+	return errors.Join(errs...)
+}
+
+func (w *TimeWindow) Validate() error {
+	var errs []error
+
+	if err := validateCron(w.Cron); err != nil {
+		errs = append(errs, err)
+	}
+
+	duration, err := time.ParseDuration(w.Duration)
+	switch {
+	case err != nil:
+		errs = append(errs, fmt.Errorf("invalid duration: %q", w.Duration))
+	case duration < time.Minute || duration > maxWindowDuration || duration%time.Minute != 0:
+		errs = append(errs, fmt.Errorf("duration must be whole minutes, between 1m and %s", maxWindowDuration))
+	}
+
+	if _, err := time.LoadLocation(w.TimeZone); err != nil {
+		errs = append(errs, fmt.Errorf("unknown timeZone: %q", w.TimeZone))
+	}
+
+	return errors.Join(errs...)
+}
+
+// cronFieldBounds are the bounds of the minute, hour, day-of-month, month & day-of-week fields (0 or 7 is Sunday).
+var cronFieldBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// validateCron validates a 5-field cron expression, of numbers, lists, ranges and steps only.
+func validateCron(expr string) error {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFieldBounds) {
+		return errors.New("cron must have 5 fields: minute hour day-of-month month day-of-week")
+	}
+
+	for idx, field := range fields {
+		for _, part := range strings.Split(field, ",") {
+			if err := validateCronPart(part, cronFieldBounds[idx][0], cronFieldBounds[idx][1]); err != nil {
+				return fmt.Errorf("cron field %q: %w", field, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateCronPart(part string, low, high int) error {
+	values, step, stepped := strings.Cut(part, "/")
+	if stepped {
+		if n, err := strconv.Atoi(step); err != nil || n <= 0 {
+			return fmt.Errorf("invalid step: %q", step)
+		}
+	}
+
+	if values == "*" {
+		return nil
+	}
+
+	from, to, ranged := strings.Cut(values, "-")
+	if !ranged {
+		to = from
+	}
+
+	fromValue, fromErr := strconv.Atoi(from)
+	toValue, toErr := strconv.Atoi(to)
+	if fromErr != nil || toErr != nil {
+		return fmt.Errorf("invalid value: %q, only numbers are supported", values)
+	}
+
+	if fromValue < low || toValue > high || fromValue > toValue {
+		return fmt.Errorf("value out of range: %q, must be within %d-%d", values, low, high)
+	}
+
+	return nil
+}
+
 func (dg *DestinationGroup) Validate() error {
 	if dg == nil {
 		return errors.New("destinationGroup is nil")
@@ -154,6 +1025,32 @@ func (dg *DestinationGroup) Validate() error {
 		}
 	}
 
+	if dg.Verification != nil {
+		if err := dg.Verification.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("validating verification: %w", err))
+		}
+	}
+
+	if dg.RolloutStrategy != nil {
+		if err := dg.RolloutStrategy.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("validating rolloutStrategy: %w", err))
+		}
+
+		canary := dg.RolloutStrategy.Canary
+		if canary != "" && !slices.ContainsFunc(dg.Destinations, func(dest Destination) bool {
+			return CoalesceSanitizeDestination(dest) == canary
+		}) {
+			errs = append(errs, fmt.Errorf("validating rolloutStrategy: canary is not a destination of the group: %s", canary))
+		}
+	}
+
+	// the deploy windows of the service are validated as inherited by each group
+	if dg.DeployWindows != nil {
+		if err := dg.DeployWindows.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("validating deployWindows: %w", err))
+		}
+	}
+
 	dsValidationErr := dg.destinationsValidatorFunc(dg.Destinations)
 	if dsValidationErr != nil {
 
@@ -180,7 +1077,7 @@ func (ds Destinations) Validate() error {
 			errs = append(errs, fmt.Errorf("validating destination: %w", err))
 		}
 
-		destFinalName := CoalesceSanitizeDestination(d) + "/namespace/" + d.Namespace
+		destFinalName := DestinationIdentity(d)
 		if _, ok := destinationsFound[destFinalName]; ok {
 			errs = append(errs, fmt.Errorf("duplicate destination name: %s", destFinalName))
 		} else {
@@ -222,6 +1119,40 @@ func (d *Destination) Validate() error {
 		errs = append(errs, errors.New("only one of clusterName or clusterUrl may be specified"))
 	}
 
+	if d.ArgoCD.Server != nil {
+		if err := d.ArgoCD.Server.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("validating argocd server: %w", err))
+		}
+	}
+
+	if d.Github.Promotion != nil {
+		if err := d.Github.Promotion.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("validating promotion: %w", err))
+		}
+	}
+
+	if d.ArgoCD.Rollback.IsEnabled() && d.Github.Promotion != nil && d.Github.Promotion.Strategy == PromotionStrategyPullRequest {
+		errs = append(errs, errors.New("rollback requires the commit promotion strategy"))
+	}
+
+	if d.Github.Environment != nil {
+		if err := d.Github.Environment.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("validating environment: %w", err))
+		}
+	}
+
+	if d.Github.Tools.ArgoCD != nil {
+		if err := d.Github.Tools.ArgoCD.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("validating argocd tool: %w", err))
+		}
+	}
+
+	if d.Github.Tools.Yq != nil {
+		if err := d.Github.Tools.Yq.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("validating yq tool: %w", err))
+		}
+	}
+
 	return errors.Join(errs...)
 }
 
@@ -237,3 +1168,9 @@ func CoalesceSanitizeDestination(destination Destination) string {
 
 	return name
 }
+
+// DestinationIdentity uniquely identifies a destination within a destination group
+// by its (sanitized) cluster and its namespace.
+func DestinationIdentity(destination Destination) string {
+	return CoalesceSanitizeDestination(destination) + "/namespace/" + destination.Namespace
+}
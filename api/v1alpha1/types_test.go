@@ -74,8 +74,262 @@ var _ = Describe("Service.Validate()", func() {
 					Expect(actualErr).To(MatchError("destination groups validation error"))
 				})
 			})
+
+			When("the service depends on itself", func() {
+				BeforeEach(func() {
+					service.DependsOn = []string{"bar", "foo"}
+				})
+
+				It("should return an error", func() {
+					Expect(actualErr).To(MatchError("service cannot depend on itself"))
+				})
+			})
+
+			When("a service dependency is duplicated", func() {
+				BeforeEach(func() {
+					service.DependsOn = []string{"bar", "bar"}
+				})
+
+				It("should return an error", func() {
+					Expect(actualErr).To(MatchError("duplicate service dependency: bar"))
+				})
+			})
+		})
+
+	})
+
+	Context("a service with destinations", func() {
+		BeforeEach(func() {
+			service.Name = "foo"
+			service.DestinationGroups = DestinationGroups{
+				{
+					Name: "staging",
+					Destinations: Destinations{
+						{
+							Name:      "in-cluster",
+							Namespace: "foo",
+							ArgoCD: ArgoCD{
+								Source: Source{
+									Path: "manifests",
+								},
+							},
+						},
+					},
+				},
+			}
+		})
+
+		It("should return no error", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+		})
+
+		When("a destination rolls back pull request promotions", func() {
+			BeforeEach(func() {
+				dest := &service.DestinationGroups[0].Destinations[0]
+				dest.ArgoCD.Rollback = &RollbackPolicy{Enabled: true}
+				dest.Github.Promotion = &Promotion{
+					Strategy:            PromotionStrategyPullRequest,
+					MergeMethod:         "squash",
+					MergeTimeoutMinutes: 30,
+				}
+			})
+
+			It("should return an error", func() {
+				Expect(actualErr).To(MatchError(ContainSubstring("rollback requires the commit promotion strategy")))
+			})
+		})
+
+		When("a group verification has a negative soak", func() {
+			BeforeEach(func() {
+				service.DestinationGroups[0].Verification = &Verification{
+					SoakSeconds: -1,
+				}
+			})
+
+			It("should return an error", func() {
+				Expect(actualErr).To(MatchError(ContainSubstring("validating verification: soakSeconds must not be negative")))
+			})
+		})
+
+		When("a group rollout strategy is invalid", func() {
+			BeforeEach(func() {
+				service.DestinationGroups[0].RolloutStrategy = &RolloutStrategy{
+					Canary:      "elsewhere",
+					Batches:     []string{"1", "150%", "a few"},
+					MaxFailures: -1,
+				}
+			})
+
+			It("should return an error", func() {
+				Expect(actualErr).To(MatchError(ContainSubstring(`validating rolloutStrategy: batch: "150%": percentage must not exceed 100%`)))
+				Expect(actualErr).To(MatchError(ContainSubstring(`batch: "a few": must be a number or a percentage`)))
+				Expect(actualErr).To(MatchError(ContainSubstring("maxFailures must not be negative")))
+				Expect(actualErr).To(MatchError(ContainSubstring("validating rolloutStrategy: canary is not a destination of the group: elsewhere")))
+			})
+		})
+
+		When("the deploy windows of a group are invalid", func() {
+			BeforeEach(func() {
+				service.DestinationGroups[0].DeployWindows = &DeployWindows{
+					Allowed: []TimeWindow{
+						{Cron: "0 22 * * MON-FRI", Duration: "8h"},
+						{Cron: "0 25 * * *", Duration: "30s", TimeZone: "Mars/Olympus_Mons"},
+					},
+					Action: "postpone",
+				}
+			})
+
+			It("should return an error", func() {
+				Expect(actualErr).To(MatchError(ContainSubstring(`validating deployWindows: allowed window: "0 22 * * MON-FRI": cron field "MON-FRI": invalid value: "MON-FRI", only numbers are supported`)))
+				Expect(actualErr).To(MatchError(ContainSubstring(`cron field "25": value out of range: "25", must be within 0-23`)))
+				Expect(actualErr).To(MatchError(ContainSubstring("duration must be whole minutes, between 1m and 168h0m0s")))
+				Expect(actualErr).To(MatchError(ContainSubstring(`unknown timeZone: "Mars/Olympus_Mons"`)))
+				Expect(actualErr).To(MatchError(ContainSubstring(`unsupported action: "postpone", must be one of: wait, skip, fail`)))
+			})
+		})
+
+		When("the deploy windows of a group declare no window", func() {
+			BeforeEach(func() {
+				service.DestinationGroups[0].DeployWindows = &DeployWindows{Action: DeployWindowActionWait}
+			})
+
+			It("should return an error", func() {
+				Expect(actualErr).To(MatchError(ContainSubstring("at least one allowed or blackout window is required")))
+			})
+		})
+
+		When("a group skips its promotions outside of its deploy windows", func() {
+			BeforeEach(func() {
+				service.DestinationGroups[0].DeployWindows = &DeployWindows{
+					Blackouts: []TimeWindow{{Cron: "0 0 24 12 *", Duration: "48h"}},
+					Action:    DeployWindowActionSkip,
+				}
+			})
+
+			It("should return no error when no other group waits for it", func() {
+				Expect(actualErr).NotTo(HaveOccurred())
+			})
+
+			When("another group waits for it", func() {
+				BeforeEach(func() {
+					prod := service.DestinationGroups[0]
+					prod.Name = "prod"
+					prod.DeployWindows = nil
+					service.DestinationGroups = append(service.DestinationGroups, prod)
+				})
+
+				It("should return an error", func() {
+					Expect(actualErr).To(MatchError(ContainSubstring("destination group: staging: deployWindows: group prod waits for it, so it must wait or fail, rather than skip")))
+				})
+			})
+		})
+
+		When("the schedule is not a cron expression", func() {
+			BeforeEach(func() {
+				service.Schedule = []Schedule{{Cron: "0 2 * *"}}
+			})
+
+			It("should return an error", func() {
+				Expect(actualErr).To(MatchError(ContainSubstring(`validating schedule: "0 2 * *": cron must have 5 fields`)))
+			})
+		})
+
+		When("destinations sharing an environment declare different protections", func() {
+			BeforeEach(func() {
+				prod := &service.DestinationGroups[0].Destinations[0]
+				prod.Github.Environment = &Environment{
+					Name: "production",
+					Protection: EnvironmentProtection{
+						RequiredReviewers: []string{"example-org/sre"},
+					},
+				}
+
+				other := *prod
+				other.Name = "other"
+				other.Github.Environment = &Environment{
+					Name: "production",
+					Protection: EnvironmentProtection{
+						WaitTimerMinutes: 10,
+					},
+				}
+
+				service.DestinationGroups[0].Destinations = append(service.DestinationGroups[0].Destinations, other)
+			})
+
+			It("should return an error", func() {
+				Expect(actualErr).To(MatchError(`environment "production": protection differs between destination group: staging: destination: in-cluster and destination group: staging: destination: other`))
+			})
+		})
+
+		When("the effective source of a destination has no path", func() {
+			BeforeEach(func() {
+				service.DestinationGroups[0].Destinations[0].ArgoCD.Source.Path = ""
+			})
+
+			It("should return an error", func() {
+				Expect(actualErr).To(MatchError("destination group: staging: destination: in-cluster: source path is required"))
+			})
+		})
+
+		When("the effective source is both helm and kustomize", func() {
+			BeforeEach(func() {
+				service.DestinationGroups[0].Destinations[0].ArgoCD.Source.Helm = &HelmSource{}
+				service.DestinationGroups[0].Destinations[0].ArgoCD.Source.Kustomize = &KustomizeSource{}
+			})
+
+			It("should return an error", func() {
+				Expect(actualErr).To(MatchError("destination group: staging: destination: in-cluster: only one of helm or kustomize may be specified"))
+			})
+		})
+
+		When("the effective sources are a multi-source", func() {
+			BeforeEach(func() {
+				service.DestinationGroups[0].Destinations[0].ArgoCD.Source = Source{}
+				service.DestinationGroups[0].Destinations[0].ArgoCD.Sources = []Source{
+					{RepoURL: "https://github.com/example/values.git", Ref: "values"},
+					{Path: "manifests"},
+				}
+			})
+
+			It("should return no error", func() {
+				Expect(actualErr).NotTo(HaveOccurred())
+			})
+
+			When("no source is promoted", func() {
+				BeforeEach(func() {
+					service.DestinationGroups[0].Destinations[0].ArgoCD.Sources[1].RepoURL = "https://github.com/example/other.git"
+				})
+
+				It("should return an error", func() {
+					Expect(actualErr).To(MatchError("destination group: staging: destination: in-cluster: at least one source must track the service repository or a helm repository"))
+				})
+			})
+
+			When("source is also specified", func() {
+				BeforeEach(func() {
+					service.DestinationGroups[0].Destinations[0].ArgoCD.Source.Path = "manifests"
+				})
+
+				It("should return an error", func() {
+					Expect(actualErr).To(MatchError("destination group: staging: destination: in-cluster: only one of source or sources may be specified"))
+				})
+			})
 		})
 
+		When("the effective source is a chart from a helm repository", func() {
+			BeforeEach(func() {
+				service.DestinationGroups[0].Destinations[0].ArgoCD.Source = Source{
+					Helm: &HelmSource{
+						RepoURL: "https://charts.example.com",
+						Chart:   "podinfo",
+					},
+				}
+			})
+
+			It("should not require a path, but should require a version", func() {
+				Expect(actualErr).To(MatchError("destination group: staging: destination: in-cluster: helm chart version is required when using a helm repository"))
+			})
+		})
 	})
 
 	When("the service is nil", func() {
@@ -253,6 +507,187 @@ var _ = Describe("DestinationGroups.Validate()", func() {
 	})
 })
 
+var _ = Describe("DestinationGroups dependencies", func() {
+	var (
+		destinationGroups DestinationGroups
+
+		actualErr error
+	)
+
+	newGroup := func(name string, dependsOn ...string) DestinationGroup {
+		return DestinationGroup{
+			Name:      name,
+			DependsOn: dependsOn,
+			destinationsValidatorFunc: func(ds Destinations) error {
+				return nil
+			},
+		}
+	}
+
+	JustBeforeEach(func() {
+		actualErr = destinationGroups.Validate()
+	})
+
+	When("no group declares dependsOn", func() {
+		BeforeEach(func() {
+			destinationGroups = DestinationGroups{
+				newGroup("dev"),
+				newGroup("staging"),
+				newGroup("prod"),
+			}
+		})
+
+		It("should chain groups in order", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(destinationGroups.Needs()).To(Equal(map[string][]string{
+				"dev":     nil,
+				"staging": {"dev"},
+				"prod":    {"staging"},
+			}))
+		})
+	})
+
+	When("groups fan out and fan in", func() {
+		BeforeEach(func() {
+			destinationGroups = DestinationGroups{
+				newGroup("staging"),
+				newGroup("prod-us", "staging"),
+				newGroup("prod-eu", "staging"),
+				newGroup("global", "prod-us", "prod-eu"),
+			}
+		})
+
+		It("should use the declared dependencies", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(destinationGroups.Needs()).To(Equal(map[string][]string{
+				"staging": nil,
+				"prod-us": {"staging"},
+				"prod-eu": {"staging"},
+				"global":  {"prod-us", "prod-eu"},
+			}))
+		})
+	})
+
+	When("only some groups declare dependsOn", func() {
+		BeforeEach(func() {
+			destinationGroups = DestinationGroups{
+				newGroup("staging"),
+				newGroup("prod-us", "staging"),
+				newGroup("prod-eu", "staging"),
+				newGroup("prod"),
+			}
+		})
+
+		It("should chain the other groups to the group listed before them", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(destinationGroups.Needs()).To(Equal(map[string][]string{
+				"staging": nil,
+				"prod-us": {"staging"},
+				"prod-eu": {"staging"},
+				"prod":    {"prod-eu"},
+			}))
+		})
+	})
+
+	When("a group depends on an unknown group", func() {
+		BeforeEach(func() {
+			destinationGroups = DestinationGroups{
+				newGroup("staging"),
+				newGroup("prod", "stagin"),
+			}
+		})
+
+		It("should return an error", func() {
+			Expect(actualErr).To(MatchError("destination group: prod: depends on unknown destination group: stagin"))
+		})
+	})
+
+	When("groups depend on each other", func() {
+		BeforeEach(func() {
+			destinationGroups = DestinationGroups{
+				newGroup("staging", "prod"),
+				newGroup("canary", "staging"),
+				newGroup("prod", "canary"),
+			}
+		})
+
+		It("should return an error", func() {
+			Expect(actualErr).To(MatchError("destination group dependency cycle: staging -> prod -> canary -> staging"))
+		})
+	})
+})
+
+var _ = Describe("DestinationGroup.Batches()", func() {
+	var group DestinationGroup
+
+	BeforeEach(func() {
+		group = DestinationGroup{Name: "prod"}
+		for idx := range 10 {
+			group.Destinations = append(group.Destinations, Destination{Name: "cluster-" + strconv.Itoa(idx)})
+		}
+	})
+
+	batchNames := func() [][]string {
+		var names [][]string
+		for _, batch := range group.Batches() {
+			var batchNames []string
+			for _, dest := range batch {
+				batchNames = append(batchNames, dest.Name)
+			}
+			names = append(names, batchNames)
+		}
+		return names
+	}
+
+	batchSizes := func() []int {
+		var sizes []int
+		for _, batch := range group.Batches() {
+			sizes = append(sizes, len(batch))
+		}
+		return sizes
+	}
+
+	When("the group has no rollout strategy", func() {
+		It("should deploy every destination at once", func() {
+			Expect(batchSizes()).To(Equal([]int{10}))
+		})
+	})
+
+	When("batches are sizes and percentages", func() {
+		BeforeEach(func() {
+			group.RolloutStrategy = &RolloutStrategy{Batches: []string{"1", "25%"}}
+		})
+
+		It("should repeat the last batch size, rounding percentages up", func() {
+			Expect(batchSizes()).To(Equal([]int{1, 3, 3, 3}))
+		})
+	})
+
+	When("batches are bounded by maxParallel", func() {
+		BeforeEach(func() {
+			group.RolloutStrategy = &RolloutStrategy{Batches: []string{"1", "100%"}, MaxParallel: 4}
+		})
+
+		It("should split the larger batches", func() {
+			Expect(batchSizes()).To(Equal([]int{1, 4, 4, 1}))
+		})
+	})
+
+	When("a canary is named", func() {
+		BeforeEach(func() {
+			group.RolloutStrategy = &RolloutStrategy{Canary: "cluster-5", MaxParallel: 5}
+		})
+
+		It("should deploy the canary on its own, first", func() {
+			Expect(batchNames()).To(Equal([][]string{
+				{"cluster-5"},
+				{"cluster-0", "cluster-1", "cluster-2", "cluster-3", "cluster-4"},
+				{"cluster-6", "cluster-7", "cluster-8", "cluster-9"},
+			}))
+		})
+	})
+})
+
 var _ = Describe("Destinations.Validate()", func() {
 	var (
 		destinations Destinations
@@ -324,6 +759,59 @@ var _ = Describe("Destinations.Validate()", func() {
 				})
 			})
 
+			When("the promotion strategy is unknown", func() {
+				BeforeEach(func() {
+					for i := range destinations {
+						destinations[i].Github.Promotion = &Promotion{
+							Strategy:            "yolo",
+							MergeMethod:         "squash",
+							MergeTimeoutMinutes: 30,
+						}
+					}
+				})
+
+				It("should return an error", func() {
+					Expect(actualErr).To(MatchError(`validating destination: validating promotion: unknown promotion strategy: "yolo"`))
+				})
+			})
+
+			When("the argocd server has multiple auth methods", func() {
+				BeforeEach(func() {
+					for i := range destinations {
+						destinations[i].ArgoCD.Server = &ArgoCDServer{
+							Address: "argocd.example.com",
+							Auth: ArgoCDAuth{
+								AuthTokenSecretName: "ARGOCD_TOKEN",
+								OIDC: &ArgoCDOIDCAuth{
+									ConnectorID: "github",
+								},
+							},
+						}
+					}
+				})
+
+				It("should return an error", func() {
+					Expect(actualErr).To(MatchError("validating destination: validating argocd server: only one of authTokenSecretName, username/password or oidc may be specified"))
+				})
+			})
+
+			When("a tool has no valid checksum", func() {
+				BeforeEach(func() {
+					for i := range destinations {
+						destinations[i].Github.Tools.Yq = &Tool{
+							Version: "v4.47.1",
+							SHA256: map[string]string{
+								"amd64": "not-a-checksum",
+							},
+						}
+					}
+				})
+
+				It("should return an error", func() {
+					Expect(actualErr).To(MatchError(`validating destination: validating yq tool: invalid sha256 checksum for amd64: "not-a-checksum"`))
+				})
+			})
+
 			When("neither server or name are provided", func() {
 				BeforeEach(func() {
 					for i := range destinations {
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/osfs"
+	"github.com/spf13/cobra"
+
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+func NewCheckCommand() *cobra.Command {
+	var outputs generateOutputs
+
+	cmd := &cobra.Command{
+		Use:   "check [service-file|directory|glob]...",
+		Short: "fail when the generated files are stale, i.e. generate would change them",
+		Long: "Regenerates the applications, workflows and environments report in memory, and compares them with the files on disk.\n" +
+			"The targetRevision of the promoted sources, which the deploy jobs write, is ignored.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			definitions, err := readServiceDefinitions(args)
+			if err != nil {
+				return err
+			}
+
+			outputs.warnings = cmd.ErrOrStderr()
+			generated, errs := generateServices(definitions, outputs)
+
+			changes, err := planChanges(osfs.New("."), outputs.paths(), func(fs billy.Filesystem) error {
+				return outputs.write(fs, generated)
+			})
+			if err != nil {
+				return fmt.Errorf("planning changes: %w", err)
+			}
+
+			var files []applicationFile
+			for _, svc := range generated.services {
+				resolved, err := resolveApplicationFiles(svc.service, svc.appRepo)
+				if err != nil {
+					return err
+				}
+				files = append(files, resolved...)
+			}
+			changes = ignorePromotedRevisions(changes, files)
+
+			if err := writeChanges(cmd.OutOrStdout(), changes); err != nil {
+				return fmt.Errorf("writing changes: %w", err)
+			}
+
+			if err := failedServices(cmd, errs); err != nil {
+				return err
+			}
+
+			if len(changes) > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d generated file(s) are stale, run generate to update them", len(changes))
+			}
+
+			return nil
+		},
+	}
+
+	outputs.addFlags(cmd)
+
+	return cmd
+}
+
+// ignorePromotedRevisions keeps the targetRevision the deploy jobs promoted to the application files,
+// dropping the changes that only differ by it.
+// Applications which cannot be read are compared as they are.
+func ignorePromotedRevisions(changes []fileChange, files []applicationFile) []fileChange {
+	var kept []fileChange
+
+	for _, change := range changes {
+		if change.Kind == changeModified {
+			for _, file := range files {
+				if file.path != change.Path {
+					continue
+				}
+
+				if after, ok := withPromotedRevisionsOf(change.Before, change.After, file); ok {
+					change.After = after
+				}
+			}
+
+			if bytes.Equal(change.Before, change.After) {
+				continue
+			}
+		}
+
+		kept = append(kept, change)
+	}
+
+	return kept
+}
+
+func withPromotedRevisionsOf(before, after []byte, file applicationFile) ([]byte, bool) {
+	beforeApp, err := argocd.ReadApplication(before)
+	if err != nil {
+		return nil, false
+	}
+
+	afterApp, err := argocd.ReadApplication(after)
+	if err != nil {
+		return nil, false
+	}
+
+	revisions := argocd.PromotedRevisionsOf(beforeApp, file.destination.ArgoCD)
+	if err := argocd.SetPromotedRevisions(&afterApp, file.destination.ArgoCD, revisions); err != nil {
+		return nil, false
+	}
+
+	fileBytes, err := util.YamlMarshalWithOptions(afterApp)
+	if err != nil {
+		return nil, false
+	}
+
+	return fileBytes, true
+}
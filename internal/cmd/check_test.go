@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
+	"github.com/lithammer/dedent"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+)
+
+var _ = Describe("checking the generated files", func() {
+	const basepath = ".alveus/applications"
+
+	var (
+		disk    billy.Filesystem
+		service v1alpha1.Service
+
+		changes   []fileChange
+		actualErr error
+	)
+
+	newService := func(namespace string) v1alpha1.Service {
+		service, err := v1alpha1.NewFromYaml([]byte(dedent.Dedent(`
+			name: podinfo
+			destinationNamespace: ` + namespace + `
+			argoCD:
+			  source:
+			    path: manifests
+			destinationGroups:
+			- name: staging
+			  destinations:
+			  - name: in-cluster
+			- name: prod
+			  destinations:
+			  - name: in-cluster
+		`)))
+		Expect(err).NotTo(HaveOccurred())
+		return service
+	}
+
+	BeforeEach(func() {
+		service = newService("podinfo")
+
+		// the deploy jobs have promoted a commit to every destination
+		appRepo, err := generateApps("https://github.com/example/podinfo.git", "abc123", basepath, service)
+		Expect(err).NotTo(HaveOccurred())
+
+		disk = memfs.New()
+		Expect(writeAppFixtures(disk, basepath, appRepo.Applications())).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		appRepo, err := generateApps("https://github.com/example/podinfo.git", "HEAD", basepath, service)
+		Expect(err).NotTo(HaveOccurred())
+
+		changes, actualErr = planChanges(disk, []string{basepath}, func(fs billy.Filesystem) error {
+			return writeAppFixtures(fs, basepath, appRepo.Applications())
+		})
+		files, err := resolveApplicationFiles(service, appRepo)
+		Expect(err).NotTo(HaveOccurred())
+		changes = ignorePromotedRevisions(changes, files)
+	})
+
+	It("should ignore the promoted targetRevision", func() {
+		Expect(actualErr).NotTo(HaveOccurred())
+		Expect(changes).To(BeEmpty())
+	})
+
+	When("the service changes", func() {
+		BeforeEach(func() {
+			service = newService("podinfo-v2")
+		})
+
+		It("should report the stale application files, keeping their promoted targetRevision", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(changes).To(HaveLen(2))
+
+			for _, change := range changes {
+				Expect(change.Kind).To(Equal(changeModified))
+
+				app, err := argocd.ReadApplication(change.After)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(app.Spec.Destination.Namespace).To(Equal("podinfo-v2"))
+				Expect(app.Spec.Source.TargetRevision).To(Equal("abc123"))
+			}
+		})
+	})
+
+	When("an application file is missing", func() {
+		BeforeEach(func() {
+			Expect(disk.Remove(".alveus/applications/podinfo-prod-in-cluster.yaml")).To(Succeed())
+		})
+
+		It("should report it", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(changes).To(HaveLen(1))
+			Expect(changes[0].Path).To(Equal(".alveus/applications/podinfo-prod-in-cluster.yaml"))
+			Expect(changes[0].Kind).To(Equal(changeCreated))
+		})
+	})
+})
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/cakehappens/gocto"
+
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+// outputClaims records which service generated each output that must be unique across the services
+// generated together, i.e. sharing a repository.
+type outputClaims struct {
+	services          map[string]string
+	applications      map[string]string
+	files             map[string]string
+	concurrencyGroups map[string]string
+}
+
+func newOutputClaims() outputClaims {
+	return outputClaims{
+		services:          make(map[string]string),
+		applications:      make(map[string]string),
+		files:             make(map[string]string),
+		concurrencyGroups: make(map[string]string),
+	}
+}
+
+// claim records the outputs of a service, unless any of them were claimed by another service already.
+func (c outputClaims) claim(owner, serviceName string, appRepo argocd.ApplicationRepository, wfs []gocto.Workflow, outputs generateOutputs) error {
+	services := map[string]string{serviceName: owner}
+	applications := make(map[string]string)
+	files := make(map[string]string)
+	concurrencyGroups := make(map[string]string)
+
+	for _, entry := range appRepo {
+		applications[util.Join("/", entry.Application.Namespace, entry.Application.Name)] = owner
+		files[filepath.Clean(entry.FilePath)] = owner
+	}
+
+	for _, wf := range wfs {
+		files[filepath.Join(outputs.workflowOutputPath, wf.GetFilename())] = owner
+
+		if wf.Concurrency.Group != "" {
+			concurrencyGroups[wf.Concurrency.Group] = owner
+		}
+
+		for _, job := range wf.Jobs {
+			if job.Concurrency.Group != "" {
+				concurrencyGroups[job.Concurrency.Group] = owner
+			}
+		}
+	}
+
+	var errs []error
+	collide := func(kind string, claimed, claiming map[string]string) {
+		for _, key := range slices.Sorted(maps.Keys(claiming)) {
+			if existing, ok := claimed[key]; ok {
+				errs = append(errs, fmt.Errorf("%s %q already generated by %s", kind, key, existing))
+			}
+		}
+	}
+
+	// the other outputs of a service of the same name collide too, so they are left out
+	collide("service", c.services, services)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	collide("application", c.applications, applications)
+	collide("file", c.files, files)
+	collide("concurrency group", c.concurrencyGroups, concurrencyGroups)
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	maps.Copy(c.services, services)
+	maps.Copy(c.applications, applications)
+	maps.Copy(c.files, files)
+	maps.Copy(c.concurrencyGroups, concurrencyGroups)
+
+	return nil
+}
@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"github.com/lithammer/dedent"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+)
+
+var _ = Describe("generating several services", func() {
+	var (
+		definitions []serviceDefinition
+		outputs     generateOutputs
+
+		generated generation
+		errs      []error
+	)
+
+	definitionOf := func(source, serviceYaml string) serviceDefinition {
+		service, err := v1alpha1.NewFromYaml([]byte(dedent.Dedent(serviceYaml)))
+		return serviceDefinition{source: source, service: service, err: err}
+	}
+
+	generatedNames := func() []string {
+		var names []string
+		for _, svc := range generated.services {
+			names = append(names, svc.service.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		outputs = generateOutputs{
+			repoURL:                "https://github.com/example/monorepo.git",
+			applicationOutputPath:  ".alveus/applications",
+			workflowOutputPath:     ".github/workflows",
+			environmentsReportPath: ".alveus/environments",
+			manifestPath:           ".alveus/manifests",
+			writeApps:              true,
+		}
+
+		definitions = []serviceDefinition{
+			definitionOf("podinfo.yaml", `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: podinfo
+				destinationGroups:
+				- name: staging
+				  destinations:
+				  - name: in-cluster
+			`),
+			definitionOf("guestbook.yaml", `
+				name: guestbook
+				destinationNamespace: guestbook
+				argoCD:
+				  source:
+				    path: guestbook
+				destinationGroups:
+				- name: staging
+				  destinations:
+				  - name: in-cluster
+			`),
+		}
+	})
+
+	JustBeforeEach(func() {
+		generated, errs = generateServices(definitions, outputs)
+	})
+
+	It("should generate every service", func() {
+		Expect(errs).To(BeEmpty())
+		Expect(generatedNames()).To(Equal([]string{"podinfo", "guestbook"}))
+	})
+
+	It("should scope the concurrency groups to each service", func() {
+		Expect(errs).To(BeEmpty())
+		for _, svc := range generated.services {
+			for _, wf := range svc.wfs {
+				if wf.Concurrency.Group != "" {
+					Expect(wf.Concurrency.Group).To(Equal(svc.service.Name + "-staging-in-cluster-" + svc.service.Name))
+				}
+			}
+		}
+	})
+
+	When("a service definition is invalid", func() {
+		BeforeEach(func() {
+			definitions = append([]serviceDefinition{definitionOf("invalid.yaml", `name: invalid`)}, definitions...)
+		})
+
+		It("should generate the other services", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(MatchError(HavePrefix("invalid.yaml: ")))
+			Expect(generatedNames()).To(Equal([]string{"podinfo", "guestbook"}))
+		})
+	})
+
+	When("two services share a name", func() {
+		BeforeEach(func() {
+			definitions = append(definitions, definitionOf("podinfo-copy.yaml", `
+				name: podinfo
+				destinationNamespace: other
+				argoCD:
+				  source:
+				    path: podinfo
+				destinationGroups:
+				- name: prod
+				  destinations:
+				  - name: in-cluster
+			`))
+		})
+
+		It("should only report the name", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(MatchError(`podinfo-copy.yaml: service podinfo: service "podinfo" already generated by service podinfo (podinfo.yaml)`))
+			Expect(generatedNames()).To(Equal([]string{"podinfo", "guestbook"}))
+		})
+	})
+
+	When("two services generate the same application", func() {
+		BeforeEach(func() {
+			// "podinfo-staging" + "in-cluster" generates the same names as "podinfo" + "staging-in-cluster"
+			definitions = append(definitions, definitionOf("podinfo-staging.yaml", `
+				name: podinfo-staging
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: podinfo
+				destinationGroups:
+				- name: in
+				  destinations:
+				  - name: cluster
+			`))
+		})
+
+		It("should report every collision", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(MatchError(ContainSubstring(`application "argocd/podinfo-staging-in-cluster" already generated by service podinfo (podinfo.yaml)`)))
+			Expect(errs[0]).To(MatchError(ContainSubstring(`file ".alveus/applications/podinfo-staging-in-cluster.yaml" already generated by service podinfo (podinfo.yaml)`)))
+			Expect(errs[0]).To(MatchError(ContainSubstring(`file ".github/workflows/alveus-podinfo-staging-in-cluster.yml" already generated by service podinfo (podinfo.yaml)`)))
+			Expect(errs[0]).To(MatchError(ContainSubstring(`concurrency group "podinfo-staging-in-cluster-podinfo" already generated by service podinfo (podinfo.yaml)`)))
+			Expect(generatedNames()).To(Equal([]string{"podinfo", "guestbook"}))
+		})
+	})
+})
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
@@ -22,94 +25,266 @@ import (
 )
 
 func NewGenerateCommand() *cobra.Command {
-	var repoURL string
-	var applicationOutputPath string
-	var workflowOutputPath string
-	var writeAppsFlag bool
+	var outputs generateOutputs
+	var dryRun bool
+	var exitCode bool
 
 	cmd := &cobra.Command{
-		Use: "generate",
+		Use:   "generate [service-file|directory|glob]...",
+		Short: "generate the applications and workflows of one or more services",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			var serviceBytes []byte
-			var err error
-
-			var serviceFile string
-			if len(args) > 0 {
-				serviceFile = args[0]
+			definitions, err := readServiceDefinitions(args)
+			if err != nil {
+				return err
 			}
 
-			if serviceFile == "" || serviceFile == "-" {
-				stat, _ := os.Stdin.Stat()
-				if (stat.Mode() & os.ModeCharDevice) == 0 {
-					serviceBytes, err = io.ReadAll(os.Stdin)
-					if err != nil {
-						return fmt.Errorf("reading stdin: %w", err)
-					}
-				} else {
-					return fmt.Errorf("stdin is from a terminal")
-				}
-			} else {
-				serviceBytes, err = os.ReadFile(serviceFile)
-				if err != nil {
-					return fmt.Errorf("reading from file: %s: %w", serviceFile, err)
-				}
-			}
+			outputs.warnings = cmd.ErrOrStderr()
+			generated, errs := generateServices(definitions, outputs)
 
-			var service v1alpha1.Service
-			{
-				service, err = v1alpha1.NewFromYaml(serviceBytes)
-				if err != nil {
-					return fmt.Errorf("constructing/validating service definition: %w", err)
+			if !dryRun {
+				if err := outputs.write(osfs.New("."), generated); err != nil {
+					errs = append(errs, err)
 				}
+
+				return failedServices(cmd, errs)
 			}
 
-			var apps []argov1alpha1.Application
-			apps, err = generateApps(repoURL, "HEAD", service)
+			changes, err := planChanges(osfs.New("."), outputs.paths(), func(fs billy.Filesystem) error {
+				return outputs.write(fs, generated)
+			})
 			if err != nil {
-				return fmt.Errorf("generating apps: %w", err)
+				return fmt.Errorf("planning changes: %w", err)
 			}
 
-			var wfs []gocto.Workflow
-
-			appRepo := make(argocd.ApplicationRepository)
-			for _, app := range apps {
-				appPath := filepath.Join(applicationOutputPath, argocd.FilenameFor(app))
-				appRepo[appPath] = app
+			if err := writeChanges(cmd.OutOrStdout(), changes); err != nil {
+				return fmt.Errorf("writing changes: %w", err)
 			}
 
-			wfs = github.NewWorkflows(service, appRepo)
-
-			{
-				fs := osfs.New(".")
-
-				if writeAppsFlag {
-					if err := writeApps(fs, applicationOutputPath, apps); err != nil {
-						return fmt.Errorf("writing apps: %w", err)
-					}
-				}
+			if err := failedServices(cmd, errs); err != nil {
+				return err
+			}
 
-				if err := writeWorkflows(fs, workflowOutputPath, wfs); err != nil {
-					return fmt.Errorf("writing workflows: %w", err)
-				}
+			if exitCode && len(changes) > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d file(s) would change", len(changes))
 			}
 
 			return nil
 		},
 	}
 
+	outputs.addFlags(cmd)
+
 	f := cmd.Flags()
-	f.StringVarP(&repoURL, "repo-url", "r", "", "URL of the repository")
+	f.BoolVar(&outputs.resetTargetRevisions, "reset-target-revisions", false, "write HEAD (and the chart versions of the service definition) as the targetRevision of the applications, instead of keeping the ones promoted to them")
+	f.BoolVar(&dryRun, "dry-run", false, "print the changes to the generated files, instead of writing them")
+	f.BoolVar(&exitCode, "exit-code", false, "with --dry-run, exit with a non-zero status when files would change")
+
+	return cmd
+}
+
+// generateOutputs configures what generate writes, and where.
+type generateOutputs struct {
+	repoURL                string
+	applicationOutputPath  string
+	workflowOutputPath     string
+	environmentsReportPath string
+	writeApps              bool
+	manifestPath           string
+	// warnings receives the files generate refuses to remove, or overwrites, as they were modified since generated.
+	warnings io.Writer
+	// resetTargetRevisions writes the generated targetRevision, instead of the one promoted to the existing application files.
+	resetTargetRevisions bool
+}
+
+func (o *generateOutputs) addFlags(cmd *cobra.Command) {
+	f := cmd.Flags()
+	f.StringVarP(&o.repoURL, "repo-url", "r", "", "URL of the repository")
 	err := cmd.MarkFlagRequired("repo-url")
 	if err != nil {
 		panic(err)
 	}
-	f.StringVar(&applicationOutputPath, "application-output-path", "./.alveus/applications", "path to where to write ArgoCD application resources")
+	f.StringVar(&o.applicationOutputPath, "application-output-path", "./.alveus/applications", "path to where to write ArgoCD application resources")
 
-	f.StringVar(&workflowOutputPath, "workflow-output-path", gocto.DefaultPathToWorkflows, "path to where to write Github workflow files")
+	f.StringVar(&o.workflowOutputPath, "workflow-output-path", gocto.DefaultPathToWorkflows, "path to where to write Github workflow files")
 
-	f.BoolVar(&writeAppsFlag, "write-apps", true, "write the applications to the output")
+	f.BoolVar(&o.writeApps, "write-apps", true, "write the applications to the output")
 
-	return cmd
+	f.StringVar(&o.environmentsReportPath, "environments-report-path", "./.alveus/environments", "path to where to write the report of Github environments which must exist in the repository settings")
+
+	f.StringVar(&o.manifestPath, "manifest-path", "./.alveus/manifests", "path to where to write the manifest of the files generated for each service, used to remove them once stale")
+}
+
+// paths are the directories generate writes to.
+func (o generateOutputs) paths() []string {
+	return []string{o.applicationOutputPath, o.workflowOutputPath, o.environmentsReportPath, o.manifestPath}
+}
+
+// serviceOutputs are the applications, workflows and environments report generated for a service.
+type serviceOutputs struct {
+	source  string
+	service v1alpha1.Service
+	appRepo argocd.ApplicationRepository
+	wfs     []gocto.Workflow
+	// schedule triggers the top-level workflow, unless the rollout workflow deploys the service.
+	schedule []v1alpha1.Schedule
+	report   []github.RequiredEnvironment
+}
+
+// generation is the outputs of the services generated together.
+type generation struct {
+	services []serviceOutputs
+	// rollout deploys the services which depend on each other, if any do.
+	rollout *rolloutOutputs
+}
+
+// generateServices generates the outputs of every service definition, in order.
+// Service definitions which are invalid, fail to generate, collide with the outputs of a service generated
+// before them, or are part of a dependency cycle, are skipped, and their errors returned.
+func generateServices(definitions []serviceDefinition, outputs generateOutputs) (generation, []error) {
+	var generated []serviceOutputs
+	var errs []error
+
+	claims := newOutputClaims()
+	for _, definition := range definitions {
+		if definition.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", definition.source, definition.err))
+			continue
+		}
+
+		svc, err := generateService(definition, outputs)
+		if err == nil {
+			owner := fmt.Sprintf("service %s (%s)", definition.service.Name, definition.source)
+			err = claims.claim(owner, definition.service.Name, svc.appRepo, svc.wfs, outputs)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: service %s: %w", definition.source, definition.service.Name, err))
+			continue
+		}
+
+		generated = append(generated, svc)
+	}
+
+	generated, dependencyErrs := resolveServiceDependencies(generated, outputs.warnings)
+	errs = append(errs, dependencyErrs...)
+
+	rollout, err := generateRollout(generated, claims, outputs)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s workflow: %w", constants.RolloutWorkflowName, err))
+	}
+
+	return generation{services: generated, rollout: rollout}, errs
+}
+
+func generateService(definition serviceDefinition, outputs generateOutputs) (serviceOutputs, error) {
+	service := definition.service
+
+	if service.Name == constants.RolloutWorkflowName {
+		return serviceOutputs{}, fmt.Errorf("service name is reserved: %s", service.Name)
+	}
+
+	appRepo, err := generateApps(outputs.repoURL, "HEAD", outputs.applicationOutputPath, service)
+	if err != nil {
+		return serviceOutputs{}, fmt.Errorf("generating apps: %w", err)
+	}
+
+	wfs, err := github.NewWorkflows(service, appRepo)
+	if err != nil {
+		return serviceOutputs{}, fmt.Errorf("generating workflows: %w", err)
+	}
+
+	return serviceOutputs{
+		source:   definition.source,
+		service:  service,
+		appRepo:  appRepo,
+		wfs:      wfs,
+		schedule: service.Schedule,
+		report:   github.NewEnvironmentsReport(service),
+	}, nil
+}
+
+// write writes the outputs of every service, carrying on with the other services when one fails.
+func (o generateOutputs) write(fs billy.Filesystem, generated generation) error {
+	rolledOut, kept, err := o.keptRollout(fs, generated)
+	if err != nil {
+		return fmt.Errorf("%s workflow: %w", constants.RolloutWorkflowName, err)
+	}
+
+	var errs []error
+	for _, svc := range generated.services {
+		if kept {
+			// the services deployed by the rollout workflow left as is must not be deployed on their own too
+			svc = svc.rolledOut(slices.Contains(rolledOut, svc.service.Name))
+		}
+
+		if err := o.writeService(fs, svc); err != nil {
+			errs = append(errs, fmt.Errorf("%s: service %s: %w", svc.source, svc.service.Name, err))
+		}
+	}
+
+	if !kept {
+		if err := o.writeRollout(fs, generated); err != nil {
+			errs = append(errs, fmt.Errorf("%s workflow: %w", constants.RolloutWorkflowName, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (o generateOutputs) writeService(fs billy.Filesystem, generated serviceOutputs) error {
+	var files []generatedFile
+	var unmanaged []fileKind
+
+	if o.writeApps {
+		apps := maps.Clone(generated.appRepo)
+		if !o.resetTargetRevisions {
+			if err := preservePromotedRevisions(fs, generated.service, apps); err != nil {
+				return fmt.Errorf("preserving promoted revisions: %w", err)
+			}
+		}
+
+		appFiles, err := renderApps(o.applicationOutputPath, apps.Applications())
+		if err != nil {
+			return fmt.Errorf("rendering apps: %w", err)
+		}
+		files = append(files, appFiles...)
+	} else {
+		unmanaged = append(unmanaged, fileKindApplication)
+	}
+
+	wfFiles, err := renderWorkflows(o.workflowOutputPath, generated.wfs, generated.schedule)
+	if err != nil {
+		return fmt.Errorf("rendering workflows: %w", err)
+	}
+	files = append(files, wfFiles...)
+
+	reportFiles, err := renderEnvironmentsReport(o.environmentsReportPath, generated.service.Name, generated.report)
+	if err != nil {
+		return fmt.Errorf("rendering environments report: %w", err)
+	}
+	files = append(files, reportFiles...)
+
+	return writeGeneratedFiles(fs, generatedFilesInput{
+		manifestFile: filepath.Join(o.manifestPath, generated.service.Name+".yaml"),
+		service:      generated.service.Name,
+		files:        files,
+		unmanaged:    unmanaged,
+		warnings:     o.warnings,
+	})
+}
+
+// failedServices reports the services which failed, returning an error when any did.
+func failedServices(cmd *cobra.Command, errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	for _, err := range errs {
+		_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "error: %v\n", err)
+	}
+
+	cmd.SilenceUsage = true
+	return fmt.Errorf("%d service(s) failed", len(errs))
 }
 
 type generateNameInput struct {
@@ -133,8 +308,8 @@ func generateNameByStrategy(input generateNameInput) string {
 	return strings.ToLower(util.Join("-", components...))
 }
 
-func generateApps(repoURL, targetRevision string, service v1alpha1.Service) ([]argov1alpha1.Application, error) {
-	var apps []argov1alpha1.Application
+func generateApps(repoURL, targetRevision, basepath string, service v1alpha1.Service) (argocd.ApplicationRepository, error) {
+	apps := make(argocd.ApplicationRepository)
 
 	for _, group := range service.DestinationGroups {
 		for _, dest := range group.Destinations {
@@ -152,79 +327,120 @@ func generateApps(repoURL, targetRevision string, service v1alpha1.Service) ([]a
 				RepoURL:        repoURL,
 				TargetRevision: targetRevision,
 				Destination:    dest,
-			}, argocd.FromServiceAPI(service))
+			},
+				argocd.FromServiceAPI(service),
+				argocd.WithSource(dest.ArgoCD.Source),
+				argocd.WithSources(dest.ArgoCD.Sources),
+			)
 
 			if err != nil {
 				return nil, fmt.Errorf("constructing application: %w", err)
 			}
 
-			apps = append(apps, app)
+			key := argocd.NewApplicationKey(service.Name, group.Name, dest)
+			appPath := filepath.Join(basepath, argocd.FilenameFor(app))
+			if err := apps.Add(key, appPath, app); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	return apps, nil
 }
 
-func writeApps(fs billy.Filesystem, basepath string, apps []argov1alpha1.Application) error {
-	if err := fs.MkdirAll(basepath, os.ModePerm); err != nil {
-		return fmt.Errorf("creating directory: %q: %w", basepath, err)
-	}
+// preservePromotedRevisions carries the targetRevision promoted to the existing application files
+// forward to the generated applications, so regenerating does not un-pin the destinations.
+// Applications without an existing file keep the generated targetRevision.
+func preservePromotedRevisions(fs billy.Filesystem, service v1alpha1.Service, appRepo argocd.ApplicationRepository) error {
+	for _, group := range service.DestinationGroups {
+		for _, dest := range group.Destinations {
+			key := argocd.NewApplicationKey(service.Name, group.Name, dest)
+			entry, err := appRepo.Get(key)
+			if err != nil {
+				return err
+			}
 
-	if err := billyutil.RemoveAll(fs, basepath); err != nil {
-		return fmt.Errorf("cleaning directory: %q: %w", basepath, err)
-	}
+			contents, err := billyutil.ReadFile(fs, entry.FilePath)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("reading application file: %q: %w", entry.FilePath, err)
+			}
 
-	for _, app := range apps {
-		filename := argocd.FilenameFor(app)
-		fullFilename := filepath.Join(basepath, filename)
-		fileBytes, err := util.YamlMarshalWithOptions(app)
-		if err != nil {
-			return fmt.Errorf("marshalling application to yaml: %w", err)
-		}
+			existing, err := argocd.ReadApplication(contents)
+			if err != nil {
+				return fmt.Errorf("reading application file: %q: %w", entry.FilePath, err)
+			}
+
+			promoted := argocd.PromotedRevisionsOf(existing, dest.ArgoCD)
+			generated := argocd.PromotedRevisionsOf(entry.Application, dest.ArgoCD)
+			revisions := argocd.PromotedRevisions{
+				Revision:     util.CoalesceStrings(promoted.Revision, generated.Revision),
+				ChartVersion: util.CoalesceStrings(promoted.ChartVersion, generated.ChartVersion),
+			}
+
+			entry.Application = *entry.Application.DeepCopy()
+			if err := argocd.SetPromotedRevisions(&entry.Application, dest.ArgoCD, revisions); err != nil {
+				return fmt.Errorf("application file: %q: %w", entry.FilePath, err)
+			}
 
-		if err := billyutil.WriteFile(fs, fullFilename, fileBytes, os.ModePerm); err != nil {
-			return fmt.Errorf("writing application to file: %q: %w", fullFilename, err)
+			appRepo[key] = entry
 		}
 	}
 
 	return nil
 }
 
-func writeWorkflows(fs billy.Filesystem, basepath string, wfs []gocto.Workflow) error {
-	if err := fs.MkdirAll(basepath, os.ModePerm); err != nil {
-		return fmt.Errorf("creating directory: %q: %w", basepath, err)
-	}
+// renderApps renders the application files.
+func renderApps(basepath string, apps []argov1alpha1.Application) ([]generatedFile, error) {
+	files := make([]generatedFile, 0, len(apps))
+	for _, app := range apps {
+		fullFilename := filepath.Join(basepath, argocd.FilenameFor(app))
+		fileBytes, err := util.YamlMarshalWithOptions(app)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling application to yaml: %w", err)
+		}
 
-	files, err := fs.ReadDir(basepath)
-	if err != nil {
-		return fmt.Errorf("reading directory: %q: %w", basepath, err)
+		files = append(files, generatedFile{path: fullFilename, kind: fileKindApplication, contents: fileBytes})
 	}
 
-	expectedPrefix := constants.Alveus + "-"
-
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
+	return files, nil
+}
 
-		if strings.HasPrefix(file.Name(), expectedPrefix) {
-			if err := fs.Remove(filepath.Join(basepath, file.Name())); err != nil {
-				return fmt.Errorf("removing file: %q: %w", file.Name(), err)
-			}
+// renderWorkflows renders the workflow files, the last (top-level) workflow being triggered on the schedule.
+func renderWorkflows(basepath string, wfs []gocto.Workflow, schedule []v1alpha1.Schedule) ([]generatedFile, error) {
+	files := make([]generatedFile, 0, len(wfs))
+	for idx, wf := range wfs {
+		var wfSchedule []v1alpha1.Schedule
+		if idx == len(wfs)-1 {
+			wfSchedule = schedule
 		}
-	}
 
-	for _, wf := range wfs {
 		fullFilename := filepath.Join(basepath, wf.GetFilename())
-		fileBytes, err := util.YamlMarshalWithOptions(wf)
+		fileBytes, err := github.MarshalWorkflow(wf, wfSchedule)
 		if err != nil {
-			return fmt.Errorf("marshalling workflow to yaml: %w", err)
+			return nil, fmt.Errorf("marshalling workflow to yaml: %w", err)
 		}
 
-		if err := billyutil.WriteFile(fs, fullFilename, fileBytes, os.ModePerm); err != nil {
-			return fmt.Errorf("writing workflow to file: %q: %w", fullFilename, err)
-		}
+		files = append(files, generatedFile{path: fullFilename, kind: fileKindWorkflow, contents: fileBytes})
 	}
 
-	return nil
+	return files, nil
+}
+
+// renderEnvironmentsReport renders the report of the environments the workflows of a service deploy to,
+// if they deploy to any.
+func renderEnvironmentsReport(basepath, serviceName string, report []github.RequiredEnvironment) ([]generatedFile, error) {
+	if len(report) == 0 {
+		return nil, nil
+	}
+
+	fullFilename := filepath.Join(basepath, serviceName+".yaml")
+	fileBytes, err := util.YamlMarshalWithOptions(report)
+	if err != nil {
+		return nil, fmt.Errorf("marshalling environments report to yaml: %w", err)
+	}
+
+	return []generatedFile{{path: fullFilename, kind: fileKindEnvironmentsReport, contents: fileBytes}}, nil
 }
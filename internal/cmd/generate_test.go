@@ -2,12 +2,62 @@ package cmd
 
 import (
 	"context"
+	"os"
+	"slices"
+	"strings"
 
+	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/cakehappens/gocto"
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
+	billyutil "github.com/go-git/go-billy/v6/util"
+	"github.com/lithammer/dedent"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/spf13/cobra"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/github"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
 )
 
+// writeAppFixtures writes the applications as generate renders them,
+// as the fixtures of the specs reading the application files.
+func writeAppFixtures(fs billy.Filesystem, basepath string, apps []argov1alpha1.Application) error {
+	files, err := renderApps(basepath, apps)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if err := billyutil.WriteFile(fs, file.path, file.contents, os.ModePerm); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// workflowNamed finds the generated workflow with the filename, failing the spec when there is none.
+func workflowNamed(wfs []gocto.Workflow, filename string) gocto.Workflow {
+	GinkgoHelper()
+
+	idx := slices.IndexFunc(wfs, func(wf gocto.Workflow) bool { return wf.GetFilename() == filename })
+	Expect(idx).To(BeNumerically(">=", 0), "no workflow: %s", filename)
+
+	return wfs[idx]
+}
+
+func stepNames(steps []gocto.Step) []string {
+	names := make([]string, 0, len(steps))
+	for _, step := range steps {
+		names = append(names, step.Name)
+	}
+
+	return names
+}
+
 var _ = Describe("NewGenerateCommand", func() {
 	var (
 		cmd       *cobra.Command
@@ -53,3 +103,897 @@ var _ = Describe("NewGenerateCommand", func() {
 		})
 	})
 })
+
+var _ = Describe("generateApps & github.NewWorkflows", func() {
+	var (
+		serviceYaml string
+		service     v1alpha1.Service
+		appRepo     argocd.ApplicationRepository
+		wfs         []gocto.Workflow
+
+		actualErr error
+	)
+
+	BeforeEach(func() {
+		serviceYaml = `
+			name: podinfo
+			destinationNamespace: podinfo
+			argoCD:
+			  source:
+			    path: manifests
+			destinationGroups:
+			- name: staging
+			  destinations:
+			  - name: in-cluster
+			  - server: https://staging.example.com
+			- name: prod
+			  destinations:
+			  - name: in-cluster
+			  - server: https://prod.example.com
+		`
+		actualErr = nil
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		service, err = v1alpha1.NewFromYaml([]byte(dedent.Dedent(serviceYaml)))
+		Expect(err).NotTo(HaveOccurred())
+
+		appRepo, actualErr = generateApps("https://github.com/example/podinfo.git", "HEAD", ".alveus/applications", service)
+		if actualErr != nil {
+			return
+		}
+		wfs, actualErr = github.NewWorkflows(service, appRepo)
+	})
+
+	When("multiple groups reuse the same destination", func() {
+		It("should not err", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+		})
+
+		It("should generate an application per group and destination", func() {
+			Expect(appRepo).To(HaveLen(4))
+		})
+
+		It("should set the source path on each application", func() {
+			for _, app := range appRepo.Applications() {
+				Expect(app.Spec.Source.Path).To(Equal("manifests"))
+			}
+		})
+
+		It("should point each destination workflow at its own group's application", func() {
+			files := make(map[string]string)
+			for _, wf := range wfs {
+				for _, job := range wf.Jobs {
+					if appFile, ok := job.Env["ARGOCD_APPLICATION_FILE"]; ok {
+						files[wf.GetFilename()] = appFile
+					}
+				}
+			}
+
+			Expect(files).To(Equal(map[string]string{
+				"alveus-podinfo-staging-in-cluster.yml":          ".alveus/applications/podinfo-staging-in-cluster.yaml",
+				"alveus-podinfo-staging-staging-example-com.yml": ".alveus/applications/podinfo-staging-staging-example-com.yaml",
+				"alveus-podinfo-prod-in-cluster.yml":             ".alveus/applications/podinfo-prod-in-cluster.yaml",
+				"alveus-podinfo-prod-prod-example-com.yml":       ".alveus/applications/podinfo-prod-prod-example-com.yaml",
+			}))
+		})
+
+		It("should pin the revision resolved by the top-level workflow through every stage", func() {
+			byFilename := make(map[string]gocto.Workflow)
+			for _, wf := range wfs {
+				byFilename[wf.GetFilename()] = wf
+			}
+
+			top := byFilename["alveus-podinfo.yml"]
+			Expect(top.On.Dispatch.Inputs).To(HaveKey("revision"))
+			Expect(top.Jobs).To(HaveKey("resolve-revision"))
+			Expect(top.Jobs["prod"].Needs).To(Equal([]string{"resolve-revision", "staging"}))
+			Expect(top.Jobs["prod"].With).To(Equal(map[string]string{
+				"revision": "${{ needs.resolve-revision.outputs.revision }}",
+			}))
+
+			group := byFilename["alveus-podinfo-prod.yml"]
+			Expect(group.On.Call.Inputs).To(HaveKey("revision"))
+			Expect(group.Jobs["in-cluster"].With).To(Equal(map[string]string{
+				"revision": "${{ inputs.revision }}",
+			}))
+
+			destination := byFilename["alveus-podinfo-prod-in-cluster.yml"]
+			Expect(destination.On.Call.Inputs).To(HaveKey("revision"))
+			Expect(destination.On.Dispatch.Inputs).To(HaveKey("revision"))
+			Expect(destination.Jobs["in-cluster"].Steps).To(ContainElement(HaveField("Run",
+				ContainSubstring(`.spec.source.targetRevision = "${{ inputs.revision }}"`))))
+		})
+
+		It("should push the promotion commit right after committing it", func() {
+			for _, wf := range wfs {
+				for _, job := range wf.Jobs {
+					commitIdx := slices.IndexFunc(job.Steps, func(step gocto.Step) bool {
+						return step.Name == "git-add-commit"
+					})
+					if commitIdx < 0 {
+						continue
+					}
+
+					push := job.Steps[commitIdx+1]
+					Expect(push.Name).To(Equal("git-push"))
+					Expect(push.Env).To(HaveKeyWithValue("PUSH_RETRY_LIMIT", "5"))
+				}
+			}
+		})
+
+		When("a destination's application is missing from the repository", func() {
+			JustBeforeEach(func() {
+				delete(appRepo, argocd.NewApplicationKey("podinfo", "prod", service.DestinationGroups[1].Destinations[0]))
+				wfs, actualErr = github.NewWorkflows(service, appRepo)
+			})
+
+			It("should err instead of falling back to another group's application", func() {
+				Expect(actualErr).To(MatchError("destination group: prod: destination: in-cluster: no application found for podinfo/prod/in-cluster/namespace/podinfo"))
+			})
+		})
+	})
+
+	When("a group overrides the source", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: base
+				    exclude: "*.md"
+				destinationGroups:
+				- name: staging
+				  destinations:
+				  - name: in-cluster
+				- name: prod
+				  argoCD:
+				    source:
+				      path: overlays/prod
+				  destinations:
+				  - name: in-cluster
+				    argoCD:
+				      source:
+				        include: "*.yaml"
+			`
+		})
+
+		It("should use the most specific source fields", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			_, staging, err := appRepo.GetByDestination("podinfo", "staging", service.DestinationGroups[0].Destinations[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(staging.Spec.Source.Path).To(Equal("base"))
+			Expect(staging.Spec.Source.Directory.Include).To(Equal("{*.yml,*.yaml}"))
+			Expect(staging.Spec.Source.Directory.Exclude).To(Equal("*.md"))
+
+			_, prod, err := appRepo.GetByDestination("podinfo", "prod", service.DestinationGroups[1].Destinations[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(prod.Spec.Source.Path).To(Equal("overlays/prod"))
+			Expect(prod.Spec.Source.Directory.Include).To(Equal("*.yaml"))
+			Expect(prod.Spec.Source.Directory.Exclude).To(Equal("*.md"))
+		})
+	})
+
+	When("destination groups declare dependencies", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: manifests
+				destinationGroups:
+				- name: staging
+				  destinations:
+				  - name: in-cluster
+				- name: prod-us
+				  dependsOn: [staging]
+				  destinations:
+				  - name: us
+				- name: prod-eu
+				  dependsOn: [staging]
+				  destinations:
+				  - name: eu
+			`
+		})
+
+		It("should compute the top-level workflow needs from the dependencies", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			top := wfs[len(wfs)-1]
+			Expect(top.GetFilename()).To(Equal("alveus-podinfo.yml"))
+			Expect(top.Jobs["staging"].Needs).To(Equal([]string{"resolve-revision"}))
+			Expect(top.Jobs["prod-us"].Needs).To(Equal([]string{"resolve-revision", "staging"}))
+			Expect(top.Jobs["prod-eu"].Needs).To(Equal([]string{"resolve-revision", "staging"}))
+		})
+	})
+
+	When("a group promotes via pull request", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: manifests
+				destinationGroups:
+				- name: staging
+				  destinations:
+				  - name: in-cluster
+				- name: prod
+				  github:
+				    promotion:
+				      strategy: pullRequest
+				      autoMerge: true
+				  destinations:
+				  - name: in-cluster
+			`
+		})
+
+		It("should push directly for other groups", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(stepNames(workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs["in-cluster"].Steps)).To(ContainElement("git-push"))
+			Expect(stepNames(workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs["in-cluster"].Steps)).NotTo(ContainElement("open-pull-request"))
+		})
+
+		It("should only deploy once the pull request is merged", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			names := stepNames(workflowNamed(wfs, "alveus-podinfo-prod-in-cluster.yml").Jobs["in-cluster"].Steps)
+			Expect(names).NotTo(ContainElement("git-push"))
+			Expect(names).To(ContainElement("auto-merge-pull-request"))
+			Expect(slices.Index(names, "git-add-commit")).To(BeNumerically("<", slices.Index(names, "open-pull-request")))
+			Expect(slices.Index(names, "open-pull-request")).To(BeNumerically("<", slices.Index(names, "wait-for-pull-request-merge")))
+			Expect(slices.Index(names, "wait-for-pull-request-merge")).To(BeNumerically("<", slices.Index(names, "argocd-upsert")))
+		})
+	})
+
+	When("the service pins its tools", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: manifests
+				github:
+				  tools:
+				    argoCD:
+				      version: v3.1.0
+				      sha256:
+				        amd64: 1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809
+				destinationGroups:
+				- name: staging
+				  destinations:
+				  - name: in-cluster
+				- name: prod
+				  github:
+				    container:
+				      image: ghcr.io/example/deploy-tools:1.0.0
+				  destinations:
+				  - name: in-cluster
+			`
+		})
+
+		It("should install the pinned version before using it", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			steps := workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs["in-cluster"].Steps
+			idx := slices.IndexFunc(steps, func(step gocto.Step) bool { return step.Name == "install-argocd" })
+			Expect(idx).To(BeNumerically(">=", 0))
+			Expect(steps[idx].Env).To(HaveKeyWithValue("TOOL_VERSION", "v3.1.0"))
+			Expect(steps[idx].Env).To(HaveKeyWithValue("TOOL_SHA256_amd64", "1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f809"))
+			Expect(steps[idx].Run).To(ContainSubstring("sha256sum --check"))
+			Expect(steps[idx-1].Uses).To(Equal("actions/cache@v4"))
+			Expect(steps[idx-1].With["key"]).To(MatchRegexp(`^alveus-argocd-v3\.1\.0-[0-9a-f]{12}-`))
+			Expect(idx).To(BeNumerically("<", slices.IndexFunc(steps, func(step gocto.Step) bool { return step.Name == "argocd-upsert" })))
+		})
+
+		It("should verify the cached binary on every run", func() {
+			steps := workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs["in-cluster"].Steps
+			idx := slices.IndexFunc(steps, func(step gocto.Step) bool { return step.Name == "install-argocd" })
+			Expect(idx).To(BeNumerically(">=", 0))
+
+			run := steps[idx].Run
+			Expect(strings.Index(run, "sha256sum --check")).To(BeNumerically(">", strings.LastIndex(run, "fi")))
+		})
+
+		It("should install a pinned yq when it is not declared", func() {
+			steps := workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs["in-cluster"].Steps
+			Expect(steps).NotTo(ContainElement(HaveField("Uses", HavePrefix("frenck/"))))
+
+			idx := slices.IndexFunc(steps, func(step gocto.Step) bool { return step.Name == "install-yq" })
+			Expect(idx).To(BeNumerically(">=", 0))
+			Expect(steps[idx].Env).To(HaveKeyWithValue("TOOL_VERSION", MatchRegexp(`^v\d+\.\d+\.\d+$`)))
+			Expect(steps[idx].Run).To(ContainSubstring(`go install "github.com/mikefarah/yq/v4@${TOOL_VERSION}"`))
+			Expect(steps[idx-1].Uses).To(Equal("actions/cache@v4"))
+		})
+
+		It("should run in the container without installing undeclared tools", func() {
+			job := workflowNamed(wfs, "alveus-podinfo-prod-in-cluster.yml").Jobs["in-cluster"]
+			Expect(job.Container.Image).To(Equal("ghcr.io/example/deploy-tools:1.0.0"))
+			Expect(job.Steps).NotTo(ContainElement(HaveField("Name", "install-yq")))
+			Expect(job.Steps).To(ContainElement(HaveField("Name", "install-argocd")))
+		})
+	})
+
+	When("a group verifies its destinations", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: manifests
+				destinationGroups:
+				- name: staging
+				  verification:
+				    soakSeconds: 600
+				    checks:
+				    - name: smoke-test
+				      run: ./scripts/smoke-test.sh
+				  destinations:
+				  - name: in-cluster
+				- name: prod
+				  destinations:
+				  - name: in-cluster
+			`
+		})
+
+		It("should wait for health, soak and run the checks after syncing", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			names := stepNames(workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs["in-cluster"].Steps)
+			syncIdx := slices.Index(names, "argocd-sync")
+			Expect(syncIdx).To(BeNumerically(">=", 0))
+			Expect(names[syncIdx+1:]).To(Equal([]string{"argocd-wait-healthy", "soak", "smoke-test"}))
+		})
+
+		It("should require the application to be healthy once the soak ends", func() {
+			steps := workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs["in-cluster"].Steps
+			soak := steps[slices.Index(stepNames(steps), "soak")]
+
+			endIdx := strings.Index(soak.Run, ">= SOAK_END")
+			Expect(endIdx).To(BeNumerically(">=", 0))
+			Expect(strings.Index(soak.Run, `!= "Healthy"`)).To(BeNumerically(">", endIdx))
+			Expect(strings.Index(soak.Run, "Healthy|Progressing")).To(BeNumerically(">", endIdx))
+		})
+
+		It("should default the health timeout", func() {
+			Expect(*service.DestinationGroups[0].Verification.HealthTimeoutSeconds).To(Equal(300))
+		})
+
+		It("should not verify groups without verification", func() {
+			Expect(stepNames(workflowNamed(wfs, "alveus-podinfo-prod-in-cluster.yml").Jobs["in-cluster"].Steps)).NotTo(ContainElement("argocd-wait-healthy"))
+		})
+	})
+
+	When("a group rolls out in batches", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: manifests
+				destinationGroups:
+				- name: prod
+				  rolloutStrategy:
+				    canary: canary
+				    batches: ["50%"]
+				    maxParallel: 2
+				  destinations:
+				  - name: us-1
+				  - name: canary
+				  - name: us-2
+				  - name: eu-1
+				  - name: eu-2
+			`
+		})
+
+		It("should deploy each batch once the previous batch has succeeded, starting with the canary", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			jobs := workflowNamed(wfs, "alveus-podinfo-prod.yml").Jobs
+			Expect(jobs).To(HaveLen(5))
+			Expect(jobs["canary"].Needs).To(BeEmpty())
+			Expect(jobs["us-1"].Needs).To(Equal([]string{"canary"}))
+			Expect(jobs["us-2"].Needs).To(Equal([]string{"canary"}))
+			Expect(jobs["eu-1"].Needs).To(Equal([]string{"us-1", "us-2"}))
+			Expect(jobs["eu-2"].Needs).To(Equal([]string{"us-1", "us-2"}))
+			Expect(jobs["eu-2"].If).To(BeEmpty())
+		})
+
+		It("should report the revision deployed to the canary", func() {
+			Expect(workflowNamed(wfs, "alveus-podinfo-prod.yml").On.Call.Outputs["revision"].Value).To(Equal("${{ jobs.canary.outputs.revision }}"))
+		})
+
+		When("failures are tolerated", func() {
+			BeforeEach(func() {
+				serviceYaml = strings.Replace(serviceYaml, "maxParallel: 2", "maxParallel: 2\n\t\t\t\t    maxFailures: 1", 1)
+			})
+
+			It("should gate each batch on the number of failed destinations", func() {
+				Expect(actualErr).NotTo(HaveOccurred())
+
+				jobs := workflowNamed(wfs, "alveus-podinfo-prod.yml").Jobs
+				Expect(jobs).To(HaveLen(7))
+
+				Expect(jobs["batch-2-gate"].Needs).To(Equal([]string{"canary"}))
+				Expect(jobs["batch-2-gate"].If).To(Equal("!cancelled() && needs.canary.result == 'success'"))
+				Expect(jobs["batch-2-gate"].Steps[0].Env).To(HaveKeyWithValue("MAX_FAILURES", "1"))
+				Expect(jobs["us-1"].Needs).To(Equal([]string{"batch-2-gate"}))
+				Expect(jobs["us-1"].If).To(Equal("!cancelled() && needs.batch-2-gate.result == 'success'"))
+
+				Expect(jobs["batch-3-gate"].Needs).To(Equal([]string{"canary", "us-1", "us-2", "batch-2-gate"}))
+				Expect(jobs["batch-3-gate"].If).To(Equal("!cancelled() && needs.batch-2-gate.result == 'success'"))
+				Expect(jobs["eu-2"].Needs).To(Equal([]string{"batch-3-gate"}))
+				Expect(jobs["eu-2"].If).To(Equal("!cancelled() && needs.batch-3-gate.result == 'success'"))
+			})
+		})
+	})
+
+	When("the service restricts its deploy windows", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: manifests
+				schedule:
+				- cron: "0 2 * * 1-5"
+				deployWindows:
+				  blackouts:
+				  - cron: "0 0 24 12 *"
+				    duration: 48h
+				    timeZone: Europe/Berlin
+				destinationGroups:
+				- name: staging
+				  destinations:
+				  - name: in-cluster
+				- name: prod
+				  deployWindows:
+				    allowed:
+				    - cron: "0 22 * * 1-5"
+				      duration: 8h
+				      timeZone: America/New_York
+				    action: wait
+				    maxWaitMinutes: 120
+				  destinations:
+				  - name: in-cluster
+			`
+		})
+
+		It("should only deploy once the deploy window job reports the window is open", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			jobs := workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs
+			Expect(jobs).To(HaveKey("deploy-window"))
+			Expect(jobs["in-cluster"].Needs).To(Equal([]string{"deploy-window"}))
+			Expect(jobs["in-cluster"].If).To(Equal("needs.deploy-window.outputs.open == 'true'"))
+		})
+
+		It("should check the windows of the group, or else of the service", func() {
+			staging := workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs["deploy-window"].Steps[0].Run
+			Expect(staging).To(ContainSubstring("ALLOWED_WINDOWS=()\nBLACKOUT_WINDOWS=('2880 Europe/Berlin 0 0 24 12 *')"))
+			Expect(staging).To(ContainSubstring("promotion is not allowed outside of the deploy windows"))
+
+			prod := workflowNamed(wfs, "alveus-podinfo-prod-in-cluster.yml").Jobs["deploy-window"]
+			Expect(prod.Steps[0].Run).To(ContainSubstring("ALLOWED_WINDOWS=('480 America/New_York 0 22 * * 1-5')\nBLACKOUT_WINDOWS=()"))
+			Expect(prod.Steps[0].Run).To(ContainSubstring("no deploy window opened within 120 minute(s)"))
+			Expect(prod.TimeoutMinutes).To(Equal(130))
+		})
+
+		It("should let the rollback workflow bypass the windows", func() {
+			destination := workflowNamed(wfs, "alveus-podinfo-prod-in-cluster.yml")
+			Expect(destination.On.Call.Inputs).To(HaveKey("bypass-deploy-window"))
+			Expect(destination.On.Dispatch.Inputs).To(HaveKey("bypass-deploy-window"))
+
+			check := destination.Jobs["deploy-window"].Steps[0]
+			Expect(check.Env).To(HaveKeyWithValue("BYPASS_DEPLOY_WINDOW", "${{ inputs.bypass-deploy-window }}"))
+			Expect(strings.Index(check.Run, `"${BYPASS_DEPLOY_WINDOW}" == "true"`)).To(BeNumerically("<", strings.Index(check.Run, "until window_open")))
+
+			rollback := workflowNamed(wfs, "alveus-podinfo-rollback.yml")
+			Expect(rollback.Jobs["prod-in-cluster"].With).To(HaveKeyWithValue("bypass-deploy-window", "${{ true }}"))
+
+			Expect(workflowNamed(wfs, "alveus-podinfo-prod.yml").Jobs["in-cluster"].With).NotTo(HaveKey("bypass-deploy-window"))
+		})
+
+		It("should trigger the top-level workflow on the schedule", func() {
+			files, err := renderWorkflows(".github/workflows", wfs, service.Schedule)
+			Expect(err).NotTo(HaveOccurred())
+
+			top := files[len(files)-1]
+			Expect(top.path).To(Equal(".github/workflows/alveus-podinfo.yml"))
+			Expect(string(top.contents)).To(ContainSubstring("\n  schedule:\n  - cron: 0 2 * * 1-5\n"))
+			Expect(string(files[0].contents)).NotTo(ContainSubstring("schedule"))
+		})
+
+		It("should promote the head of the commit branch on schedule", func() {
+			resolve := workflowNamed(wfs, "alveus-podinfo.yml").Jobs["resolve-revision"]
+			Expect(resolve.Steps[1].Run).To(ContainSubstring(`if [[ "${GITHUB_EVENT_NAME}" == "schedule" ]]; then` + "\n" +
+				"  DEFAULT_REVISION=$(git rev-parse HEAD)\n"))
+		})
+	})
+
+	When("the service rolls back failed promotions", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  rollback:
+				    enabled: true
+				  source:
+				    path: manifests
+				destinationGroups:
+				- name: staging
+				  verification: {}
+				  destinations:
+				  - name: in-cluster
+				- name: prod
+				  argoCD:
+				    rollback:
+				      enabled: false
+				  destinations:
+				  - name: in-cluster
+			`
+		})
+
+		It("should revert, push and re-sync only after a pushed promotion fails", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			steps := workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs["in-cluster"].Steps
+			rollbackIdx := slices.IndexFunc(steps, func(step gocto.Step) bool { return step.Name == "rollback-revert" })
+			Expect(rollbackIdx).To(BeNumerically(">", slices.IndexFunc(steps, func(step gocto.Step) bool { return step.Name == "argocd-wait-healthy" })))
+
+			var names []string
+			for _, step := range steps[rollbackIdx:] {
+				names = append(names, step.Name)
+				Expect(step.If).To(Equal("failure() && steps.git-add-commit.outputs.committed == 'true' && steps.git-push.outcome == 'success'"))
+			}
+			Expect(names).To(Equal([]string{"rollback-revert", "rollback-git-push", "rollback-argocd-upsert", "rollback-argocd-sync"}))
+		})
+
+		It("should allow a group to opt out", func() {
+			Expect(workflowNamed(wfs, "alveus-podinfo-prod-in-cluster.yml").Jobs["in-cluster"].Steps).NotTo(ContainElement(HaveField("Name", "rollback-revert")))
+		})
+	})
+
+	When("groups deploy to github environments", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: manifests
+				destinationGroups:
+				- name: staging
+				  destinations:
+				  - name: in-cluster
+				- name: prod
+				  github:
+				    environment:
+				      name: production
+				      protection:
+				        requiredReviewers: [example-org/sre]
+				  destinations:
+				  - name: in-cluster
+				    github:
+				      environment:
+				        name: production
+				        url: https://podinfo.example.com
+				  - server: https://prod.example.com
+			`
+		})
+
+		It("should deploy each destination in its environment", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			environments := make(map[string]gocto.Environment)
+			for _, wf := range wfs {
+				for _, job := range wf.Jobs {
+					if len(job.Steps) > 0 {
+						environments[wf.GetFilename()] = job.Environment
+					}
+				}
+			}
+
+			Expect(environments).To(HaveKeyWithValue("alveus-podinfo-staging-in-cluster.yml", gocto.Environment{}))
+			Expect(environments).To(HaveKeyWithValue("alveus-podinfo-prod-in-cluster.yml", gocto.Environment{
+				Name: "production",
+				URL:  "https://podinfo.example.com",
+			}))
+			Expect(environments).To(HaveKeyWithValue("alveus-podinfo-prod-prod-example-com.yml", gocto.Environment{
+				Name: "production",
+			}))
+		})
+
+		It("should report the environments which must exist", func() {
+			Expect(github.NewEnvironmentsReport(service)).To(Equal([]github.RequiredEnvironment{
+				{
+					Name: "production",
+					Protection: v1alpha1.EnvironmentProtection{
+						RequiredReviewers: []string{"example-org/sre"},
+					},
+					Destinations: []string{"prod/in-cluster", "prod/prod-example-com"},
+				},
+			}))
+		})
+	})
+
+	When("a destination is managed by a different argo cd instance", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: manifests
+				  server:
+				    address: argocd.example.com
+				    auth:
+				      authTokenSecretName: ARGOCD_TOKEN
+				destinationGroups:
+				- name: staging
+				  destinations:
+				  - name: in-cluster
+				  - name: edge
+				    argoCD:
+				      server:
+				        address: argocd.edge.example.com
+				        auth:
+				          oidc:
+				            connectorID: github-actions
+			`
+		})
+
+		It("should authenticate each destination with its own instance", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			inCluster := workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs["in-cluster"]
+			Expect(inCluster.Env).To(HaveKeyWithValue("ARGOCD_SERVER", "argocd.example.com"))
+			Expect(inCluster.Env).To(HaveKeyWithValue("ARGOCD_AUTH_TOKEN", "${{ secrets.ARGOCD_TOKEN }}"))
+			Expect(inCluster.Permissions).To(BeZero())
+
+			edge := workflowNamed(wfs, "alveus-podinfo-staging-edge.yml").Jobs["edge"]
+			Expect(edge.Env).To(HaveKeyWithValue("ARGOCD_SERVER", "argocd.edge.example.com"))
+			Expect(edge.Env).NotTo(HaveKey("ARGOCD_AUTH_TOKEN"))
+			Expect(edge.Steps).To(ContainElement(HaveField("Name", "argocd-oidc-login")))
+			Expect(edge.Permissions.IDToken).To(Equal(gocto.AccessLevelWrite))
+		})
+
+		It("should grant the permissions the deploy jobs request through the calling jobs", func() {
+			Expect(workflowNamed(wfs, "alveus-podinfo-staging.yml").Jobs["edge"].Permissions.IDToken).To(Equal(gocto.AccessLevelWrite))
+			Expect(workflowNamed(wfs, "alveus-podinfo-staging.yml").Jobs["in-cluster"].Permissions).To(BeZero())
+			Expect(workflowNamed(wfs, "alveus-podinfo.yml").Jobs["staging"].Permissions.IDToken).To(Equal(gocto.AccessLevelWrite))
+		})
+
+		When("another destination of the group promotes via pull request", func() {
+			BeforeEach(func() {
+				serviceYaml = strings.Replace(serviceYaml, "  - name: in-cluster\n",
+					"  - name: in-cluster\n\t\t\t\t    github:\n\t\t\t\t      promotion:\n\t\t\t\t        strategy: pullRequest\n", 1)
+			})
+
+			It("should grant the pull request permissions, regardless of OIDC", func() {
+				Expect(actualErr).NotTo(HaveOccurred())
+				Expect(workflowNamed(wfs, "alveus-podinfo-staging-in-cluster.yml").Jobs["in-cluster"].Permissions).To(Equal(gocto.Permissions{
+					Contents:     gocto.AccessLevelWrite,
+					PullRequests: gocto.AccessLevelWrite,
+				}))
+				Expect(workflowNamed(wfs, "alveus-podinfo.yml").Jobs["staging"].Permissions).To(Equal(gocto.Permissions{
+					Contents:     gocto.AccessLevelWrite,
+					IDToken:      gocto.AccessLevelWrite,
+					PullRequests: gocto.AccessLevelWrite,
+				}))
+			})
+		})
+	})
+
+	When("the service has multiple sources", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  sources:
+				  - helm:
+				      repoURL: https://charts.example.com
+				      chart: podinfo
+				      version: 6.9.2
+				      valueFiles:
+				      - $values/values/podinfo.yaml
+				  - repoURL: https://github.com/example/values.git
+				    targetRevision: main
+				    ref: values
+				  - path: manifests
+				destinationGroups:
+				- name: staging
+				  destinations:
+				  - name: in-cluster
+			`
+		})
+
+		It("should generate a multi-source application", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			_, app, err := appRepo.GetByDestination("podinfo", "staging", service.DestinationGroups[0].Destinations[0])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(app.Spec.Source).To(BeNil())
+			Expect(app.Spec.Sources).To(HaveLen(3))
+			Expect(app.Spec.Sources[0].RepoURL).To(Equal("https://charts.example.com"))
+			Expect(app.Spec.Sources[0].TargetRevision).To(Equal("6.9.2"))
+			Expect(app.Spec.Sources[1].RepoURL).To(Equal("https://github.com/example/values.git"))
+			Expect(app.Spec.Sources[1].TargetRevision).To(Equal("main"))
+			Expect(app.Spec.Sources[1].Ref).To(Equal("values"))
+			Expect(app.Spec.Sources[2].RepoURL).To(Equal("https://github.com/example/podinfo.git"))
+			Expect(app.Spec.Sources[2].Path).To(Equal("manifests"))
+		})
+
+		It("should only promote the sources tracking the service repository or a helm repository", func() {
+			var run string
+			for _, wf := range wfs {
+				for _, job := range wf.Jobs {
+					for _, step := range job.Steps {
+						if step.Name == "update-application-yaml" {
+							run = step.Run
+						}
+					}
+				}
+			}
+
+			Expect(run).To(ContainSubstring(`'.spec.sources[0].targetRevision = "6.9.2" | .spec.sources[2].targetRevision = "${{ inputs.revision }}"'`))
+		})
+	})
+
+	When("two destinations generate the same application name", func() {
+		BeforeEach(func() {
+			serviceYaml = `
+				name: podinfo
+				destinationNamespace: podinfo
+				argoCD:
+				  source:
+				    path: manifests
+				destinationGroups:
+				- name: prod-us
+				  destinations:
+				  - name: east
+				- name: prod
+				  destinations:
+				  - name: us-east
+			`
+		})
+
+		It("should err", func() {
+			Expect(actualErr).To(MatchError(`application file ".alveus/applications/podinfo-prod-us-east.yaml" claimed by both podinfo/prod-us/east/namespace/podinfo and podinfo/prod/us-east/namespace/podinfo`))
+		})
+	})
+})
+
+var _ = Describe("regenerating the applications", func() {
+	const (
+		basepath    = ".alveus/applications"
+		stagingFile = ".alveus/applications/podinfo-staging-in-cluster.yaml"
+		prodFile    = ".alveus/applications/podinfo-prod-in-cluster.yaml"
+	)
+
+	var (
+		fs      billy.Filesystem
+		service v1alpha1.Service
+		outputs generateOutputs
+
+		actualErr error
+	)
+
+	readApp := func(path string) argov1alpha1.Application {
+		contents, err := billyutil.ReadFile(fs, path)
+		Expect(err).NotTo(HaveOccurred())
+		app, err := argocd.ReadApplication(contents)
+		Expect(err).NotTo(HaveOccurred())
+		return app
+	}
+
+	BeforeEach(func() {
+		var err error
+		service, err = v1alpha1.NewFromYaml([]byte(dedent.Dedent(`
+			name: podinfo
+			destinationNamespace: podinfo
+			argoCD:
+			  sources:
+			  - helm:
+			      repoURL: https://charts.example.com
+			      chart: podinfo
+			      version: 6.9.2
+			  - repoURL: https://github.com/example/values.git
+			    targetRevision: main
+			    ref: values
+			  - path: manifests
+			destinationGroups:
+			- name: staging
+			  destinations:
+			  - name: in-cluster
+			- name: prod
+			  destinations:
+			  - name: in-cluster
+		`)))
+		Expect(err).NotTo(HaveOccurred())
+
+		outputs = generateOutputs{
+			repoURL:                "https://github.com/example/podinfo.git",
+			applicationOutputPath:  basepath,
+			workflowOutputPath:     ".github/workflows",
+			environmentsReportPath: ".alveus/environments",
+			writeApps:              true,
+		}
+
+		// staging was promoted, prod was never deployed
+		appRepo, err := generateApps(outputs.repoURL, "HEAD", basepath, service)
+		Expect(err).NotTo(HaveOccurred())
+		_, promoted, err := appRepo.GetByDestination("podinfo", "staging", service.DestinationGroups[0].Destinations[0])
+		Expect(err).NotTo(HaveOccurred())
+		promoted.Spec.Sources[0].TargetRevision = "6.9.1"
+		promoted.Spec.Sources[1].TargetRevision = "stale"
+		promoted.Spec.Sources[2].TargetRevision = "abc123"
+
+		fileBytes, err := util.YamlMarshalWithOptions(promoted)
+		Expect(err).NotTo(HaveOccurred())
+		fs = memfs.New()
+		Expect(billyutil.WriteFile(fs, stagingFile, fileBytes, os.ModePerm)).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		generated, errs := generateServices([]serviceDefinition{{source: "podinfo.yaml", service: service}}, outputs)
+		Expect(errs).To(BeEmpty())
+		actualErr = outputs.write(fs, generated)
+	})
+
+	It("should keep the revisions promoted to the existing application files", func() {
+		Expect(actualErr).NotTo(HaveOccurred())
+
+		sources := readApp(stagingFile).Spec.Sources
+		Expect(sources[0].TargetRevision).To(Equal("6.9.1"))
+		Expect(sources[2].TargetRevision).To(Equal("abc123"))
+	})
+
+	It("should regenerate the sources which are not promoted", func() {
+		Expect(actualErr).NotTo(HaveOccurred())
+		Expect(readApp(stagingFile).Spec.Sources[1].TargetRevision).To(Equal("main"))
+	})
+
+	It("should generate new application files at the generated revisions", func() {
+		Expect(actualErr).NotTo(HaveOccurred())
+
+		sources := readApp(prodFile).Spec.Sources
+		Expect(sources[0].TargetRevision).To(Equal("6.9.2"))
+		Expect(sources[2].TargetRevision).To(Equal("HEAD"))
+	})
+
+	When("the target revisions are reset", func() {
+		BeforeEach(func() {
+			outputs.resetTargetRevisions = true
+		})
+
+		It("should write the generated revisions", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+
+			sources := readApp(stagingFile).Spec.Sources
+			Expect(sources[0].TargetRevision).To(Equal("6.9.2"))
+			Expect(sources[2].TargetRevision).To(Equal("HEAD"))
+		})
+	})
+
+	When("an existing application file is invalid", func() {
+		BeforeEach(func() {
+			Expect(billyutil.WriteFile(fs, stagingFile, []byte("spec: ["), os.ModePerm)).To(Succeed())
+		})
+
+		It("should err instead of resetting it", func() {
+			Expect(actualErr).To(MatchError(ContainSubstring(`reading application file: "` + stagingFile + `"`)))
+		})
+	})
+})
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/git"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+func NewHistoryCommand() *cobra.Command {
+	var applicationOutputPath string
+	var repositoryPath string
+	var outputFormat string
+	var group string
+	var destination string
+
+	cmd := &cobra.Command{
+		Use:   "history [service-file]",
+		Short: "show when each revision was promoted to each destination",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			service, err := readService(args)
+			if err != nil {
+				return err
+			}
+
+			repo, err := git.Open(repositoryPath)
+			if err != nil {
+				return err
+			}
+
+			resolved, err := readApplicationFiles(service, applicationOutputPath)
+			if err != nil {
+				return err
+			}
+
+			var files []applicationFile
+			for _, file := range resolved {
+				if group != "" && file.group != group {
+					continue
+				}
+
+				if destination != "" && v1alpha1.CoalesceSanitizeDestination(file.destination) != destination {
+					continue
+				}
+
+				files = append(files, file)
+			}
+
+			if len(files) == 0 {
+				return fmt.Errorf("no destinations match group: %q, destination: %q", group, destination)
+			}
+
+			history, err := promotionHistory(repo, files, service.DestinationGroups.FinalGroups())
+			if err != nil {
+				return err
+			}
+
+			return writeOutput(cmd.OutOrStdout(), outputFormat, history, func(tw *tabwriter.Writer) {
+				_, _ = fmt.Fprintln(tw, "PROMOTED\tGROUP\tDESTINATION\tREVISION\tBY\tLEAD TIME")
+				for _, promotion := range history.Promotions {
+					_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+						promotion.PromotedAt.Format("2006-01-02 15:04"), promotion.Group, promotion.Destination,
+						shortRevision(promotion.Revision, promotion.ChartVersion), promotion.PromotedBy, promotion.LeadTime)
+				}
+
+				_, _ = fmt.Fprintln(tw)
+				_, _ = fmt.Fprintln(tw, "REVISION\tCOMMITTED\tFIRST DEPLOYED\tLAST STAGE DEPLOYED\tLEAD TIME\tSUBJECT")
+				for _, revision := range history.Revisions {
+					var committed, subject, lastStageDeployed string
+					if revision.Commit != nil {
+						committed = revision.Commit.Date.Format("2006-01-02 15:04")
+						subject = revision.Commit.Subject
+					}
+
+					if revision.LastStageDeployedAt != nil {
+						lastStageDeployed = revision.LastStageDeployedAt.Format("2006-01-02 15:04")
+					}
+
+					_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+						shortRevision(revision.Revision, revision.ChartVersion), committed,
+						revision.FirstDeployedAt.Format("2006-01-02 15:04"), lastStageDeployed, revision.LeadTime, subject)
+				}
+			})
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&applicationOutputPath, "application-output-path", "./.alveus/applications", "path to where the ArgoCD application resources were written")
+	f.StringVar(&repositoryPath, "repository-path", ".", "path to the git repository of the service")
+	f.StringVarP(&outputFormat, "output", "o", outputFormatTable, "output format, one of: table, json, yaml")
+	f.StringVarP(&group, "group", "g", "", "only show the history of this destination group")
+	f.StringVarP(&destination, "destination", "d", "", "only show the history of this destination")
+
+	return cmd
+}
+
+type history struct {
+	// Promotions of the destinations, oldest first.
+	Promotions []promotion `json:"promotions"`
+	// Revisions promoted, in the order they were first deployed.
+	Revisions []revisionHistory `json:"revisions"`
+}
+
+// promotion is a change of the promoted targetRevision(s) of the application file of a destination.
+type promotion struct {
+	Group           string    `json:"group"`
+	Destination     string    `json:"destination"`
+	Application     string    `json:"application"`
+	Revision        string    `json:"revision,omitempty"`
+	ChartVersion    string    `json:"chartVersion,omitempty"`
+	PromotedAt      time.Time `json:"promotedAt"`
+	PromotedBy      string    `json:"promotedBy"`
+	PromotionCommit string    `json:"promotionCommit"`
+	// LeadTime is the time from the commit of the revision to its promotion, when the commit is known.
+	LeadTime string `json:"leadTime,omitempty"`
+}
+
+type revisionHistory struct {
+	Revision        string      `json:"revision,omitempty"`
+	ChartVersion    string      `json:"chartVersion,omitempty"`
+	Commit          *git.Commit `json:"commit,omitempty"`
+	FirstDeployedAt time.Time   `json:"firstDeployedAt"`
+	// LastStageDeployedAt is when the revision first reached a destination of the last stage(s) of the rollout.
+	LastStageDeployedAt *time.Time `json:"lastStageDeployedAt,omitempty"`
+	// LeadTime is the time from the commit of the revision to LastStageDeployedAt.
+	LeadTime string `json:"leadTime,omitempty"`
+}
+
+// promotionHistory reconstructs the promotions of each destination from the git history of its application file.
+func promotionHistory(repo *git.Repository, files []applicationFile, finalGroups []string) (history, error) {
+	result := history{
+		Promotions: []promotion{},
+		Revisions:  []revisionHistory{},
+	}
+
+	commits := make(map[string]*git.Commit)
+	commitOf := func(revision string) *git.Commit {
+		if !git.IsCommitHash(revision) {
+			return nil
+		}
+
+		if commit, ok := commits[revision]; ok {
+			return commit
+		}
+
+		var found *git.Commit
+		if commit, err := repo.Commit(revision); err == nil {
+			found = &commit
+		}
+		commits[revision] = found
+
+		return found
+	}
+
+	for _, file := range files {
+		fileHistory, err := repo.FileHistory(file.path)
+		if err != nil {
+			return history{}, fmt.Errorf("destination group: %s: destination: %s: %w",
+				file.group, v1alpha1.CoalesceSanitizeDestination(file.destination), err)
+		}
+
+		var previous argocd.PromotedRevisions
+		for _, fileRevision := range fileHistory {
+			app, err := argocd.ReadApplication(fileRevision.Contents)
+			if err != nil {
+				// e.g. a hand-written file, before the file was generated
+				continue
+			}
+
+			revisions := argocd.PromotedRevisionsOf(app, file.destination.ArgoCD)
+			if revisions == previous {
+				continue
+			}
+			previous = revisions
+
+			p := promotion{
+				Group:           file.group,
+				Destination:     v1alpha1.CoalesceSanitizeDestination(file.destination),
+				Application:     app.Name,
+				Revision:        revisions.Revision,
+				ChartVersion:    revisions.ChartVersion,
+				PromotedAt:      fileRevision.Commit.Date,
+				PromotedBy:      fileRevision.Commit.Author,
+				PromotionCommit: fileRevision.Commit.Hash,
+			}
+
+			if commit := commitOf(revisions.Revision); commit != nil {
+				p.LeadTime = formatLeadTime(fileRevision.Commit.Date.Sub(commit.Date))
+			}
+
+			result.Promotions = append(result.Promotions, p)
+		}
+	}
+
+	slices.SortStableFunc(result.Promotions, func(a, b promotion) int {
+		return a.PromotedAt.Compare(b.PromotedAt)
+	})
+
+	for _, p := range result.Promotions {
+		idx := slices.IndexFunc(result.Revisions, func(r revisionHistory) bool {
+			return r.Revision == p.Revision && r.ChartVersion == p.ChartVersion
+		})
+		if idx < 0 {
+			result.Revisions = append(result.Revisions, revisionHistory{
+				Revision:        p.Revision,
+				ChartVersion:    p.ChartVersion,
+				Commit:          commitOf(p.Revision),
+				FirstDeployedAt: p.PromotedAt,
+			})
+			idx = len(result.Revisions) - 1
+		}
+
+		revision := &result.Revisions[idx]
+		if revision.LastStageDeployedAt == nil && slices.Contains(finalGroups, p.Group) {
+			revision.LastStageDeployedAt = util.Ptr(p.PromotedAt)
+			if revision.Commit != nil {
+				revision.LeadTime = formatLeadTime(p.PromotedAt.Sub(revision.Commit.Date))
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func formatLeadTime(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// shortRevision abbreviates commit hashes, like git does.
+func shortRevision(revision, chartVersion string) string {
+	if len(revision) == 40 {
+		revision = revision[:7]
+	}
+
+	return formatRevisions(revision, chartVersion)
+}
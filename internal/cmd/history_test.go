@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/osfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/lithammer/dedent"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/git"
+)
+
+var _ = Describe("promotionHistory", func() {
+	const basepath = ".alveus/applications"
+
+	var (
+		fs       billy.Filesystem
+		worktree *gogit.Worktree
+		repo     *git.Repository
+		service  v1alpha1.Service
+		files    []applicationFile
+
+		start    time.Time
+		revision string
+
+		actual    history
+		actualErr error
+	)
+
+	commitAll := func(message, author string, when time.Time) string {
+		Expect(worktree.AddGlob(".")).To(Succeed())
+		signature := &object.Signature{Name: author, Email: author + "@example.com", When: when}
+		hash, err := worktree.Commit(message, &gogit.CommitOptions{Author: signature, Committer: signature})
+		Expect(err).NotTo(HaveOccurred())
+		return hash.String()
+	}
+
+	promote := func(group string, when time.Time) {
+		_, err := rollbackApps(fs, rollbackInput{
+			files:     files,
+			group:     group,
+			revisions: argocd.PromotedRevisions{Revision: revision},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		commitAll("feat("+group+"): deploy", "github-actions", when)
+	}
+
+	BeforeEach(func() {
+		var err error
+		service, err = v1alpha1.NewFromYaml([]byte(dedent.Dedent(`
+			name: podinfo
+			destinationNamespace: podinfo
+			argoCD:
+			  source:
+			    path: manifests
+			destinationGroups:
+			- name: staging
+			  destinations:
+			  - name: in-cluster
+			- name: prod
+			  destinations:
+			  - name: in-cluster
+		`)))
+		Expect(err).NotTo(HaveOccurred())
+
+		repoPath := GinkgoT().TempDir()
+		gitRepo, err := gogit.PlainInit(repoPath, false)
+		Expect(err).NotTo(HaveOccurred())
+		worktree, err = gitRepo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		repo, err = git.Open(repoPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		// the repository is not the working directory, so everything is addressed by absolute path
+		fs = osfs.New("/")
+		appRepo, err := generateApps("https://github.com/example/podinfo.git", "HEAD", filepath.Join(repoPath, basepath), service)
+		Expect(err).NotTo(HaveOccurred())
+		files, err = resolveApplicationFiles(service, appRepo)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(writeAppFixtures(fs, filepath.Join(repoPath, basepath), appRepo.Applications())).To(Succeed())
+
+		start = time.Date(2025, 10, 1, 10, 0, 0, 0, time.UTC)
+		commitAll("chore: generate", "dev", start)
+		Expect(fs.MkdirAll(filepath.Join(repoPath, "src"), 0o755)).To(Succeed())
+		f, err := fs.Create(filepath.Join(repoPath, "src", "main.go"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+		revision = commitAll("feat: add main", "dev", start.Add(time.Hour))
+
+		promote("staging", start.Add(time.Hour+10*time.Minute))
+		promote("prod", start.Add(3*time.Hour))
+	})
+
+	JustBeforeEach(func() {
+		actual, actualErr = promotionHistory(repo, files, service.DestinationGroups.FinalGroups())
+	})
+
+	It("should list the promotions of each destination, oldest first", func() {
+		Expect(actualErr).NotTo(HaveOccurred())
+
+		var timeline []string
+		for _, p := range actual.Promotions {
+			timeline = append(timeline, p.Group+"@"+p.Revision+" by "+p.PromotedBy+" after "+p.LeadTime)
+		}
+		Expect(timeline).To(Equal([]string{
+			"staging@HEAD by dev after ",
+			"prod@HEAD by dev after ",
+			"staging@" + revision + " by github-actions after 10m0s",
+			"prod@" + revision + " by github-actions after 2h0m0s",
+		}))
+	})
+
+	It("should compute the lead time of each revision to the last stage", func() {
+		Expect(actual.Revisions).To(HaveLen(2))
+
+		promoted := actual.Revisions[1]
+		Expect(promoted.Revision).To(Equal(revision))
+		Expect(promoted.Commit.Subject).To(Equal("feat: add main"))
+		Expect(promoted.FirstDeployedAt).To(BeTemporally("==", start.Add(time.Hour+10*time.Minute)))
+		Expect(*promoted.LastStageDeployedAt).To(BeTemporally("==", start.Add(3*time.Hour)))
+		Expect(promoted.LeadTime).To(Equal("2h0m0s"))
+	})
+})
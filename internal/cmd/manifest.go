@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-billy/v6"
+	billyutil "github.com/go-git/go-billy/v6/util"
+	"sigs.k8s.io/yaml"
+
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+type fileKind string
+
+const (
+	fileKindApplication        fileKind = "application"
+	fileKindWorkflow           fileKind = "workflow"
+	fileKindEnvironmentsReport fileKind = "environments-report"
+)
+
+// generatedFile is a file generate writes for a service.
+type generatedFile struct {
+	path     string
+	kind     fileKind
+	contents []byte
+}
+
+// manifest records the files generated for a service, so that generate only ever removes files it owns.
+type manifest struct {
+	Service string `json:"service"`
+	// Services are the services the files were generated for, when they are not generated for a single service.
+	Services []string    `json:"services,omitempty"`
+	Files    []ownedFile `json:"files"`
+}
+
+type ownedFile struct {
+	Path string   `json:"path"`
+	Kind fileKind `json:"kind"`
+	// SHA256 is the checksum of the contents generated, see hashContents.
+	SHA256 string `json:"sha256"`
+}
+
+type generatedFilesInput struct {
+	manifestFile string
+	service      string
+	services     []string
+	files        []generatedFile
+	// unmanaged are the kinds of files not generated this time, whose ownership is kept as is.
+	unmanaged []fileKind
+	warnings  io.Writer
+}
+
+// writeGeneratedFiles writes the files generated for a service, and removes the files generated previously which
+// are now stale, as recorded by the manifest of the service.
+// Files modified since they were generated are never removed, and a warning is written when they are overwritten.
+// Files written before the service had a manifest are adopted, but never removed.
+func writeGeneratedFiles(fs billy.Filesystem, input generatedFilesInput) error {
+	warnings := input.warnings
+	if warnings == nil {
+		warnings = io.Discard
+	}
+
+	previous, found, err := readManifest(fs, input.manifestFile)
+	if err != nil {
+		return err
+	}
+
+	owned := make(map[string]ownedFile, len(previous.Files))
+	for _, file := range previous.Files {
+		owned[file.Path] = file
+	}
+
+	next := manifest{Service: input.service, Services: input.services}
+
+	generated := make(map[string]bool, len(input.files))
+	for _, file := range input.files {
+		generated[filepath.Clean(file.path)] = true
+	}
+
+	for _, file := range previous.Files {
+		if generated[file.Path] {
+			continue
+		}
+
+		if slices.Contains(input.unmanaged, file.Kind) {
+			next.Files = append(next.Files, file)
+			continue
+		}
+
+		contents, err := billyutil.ReadFile(fs, file.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading file: %q: %w", file.Path, err)
+		}
+
+		if hashContents(file.Kind, contents) != file.SHA256 {
+			_, _ = fmt.Fprintf(warnings, "warning: not removing %s: modified since it was generated\n", file.Path)
+			continue
+		}
+
+		if err := fs.Remove(file.Path); err != nil {
+			return fmt.Errorf("removing file: %q: %w", file.Path, err)
+		}
+	}
+
+	for _, file := range input.files {
+		path := filepath.Clean(file.path)
+
+		contents, err := billyutil.ReadFile(fs, path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+		case err != nil:
+			return fmt.Errorf("reading file: %q: %w", path, err)
+		default:
+			entry, isOwned := owned[path]
+			if isOwned && hashContents(entry.Kind, contents) != entry.SHA256 {
+				_, _ = fmt.Fprintf(warnings, "warning: overwriting %s: modified since it was generated\n", path)
+			}
+
+			if !isOwned && found {
+				_, _ = fmt.Fprintf(warnings, "warning: overwriting %s: not generated for service %s\n", path, input.service)
+			}
+		}
+
+		if err := fs.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return fmt.Errorf("creating directory: %q: %w", filepath.Dir(path), err)
+		}
+
+		if err := billyutil.WriteFile(fs, path, file.contents, os.ModePerm); err != nil {
+			return fmt.Errorf("writing file: %q: %w", path, err)
+		}
+
+		next.Files = append(next.Files, ownedFile{
+			Path:   path,
+			Kind:   file.kind,
+			SHA256: hashContents(file.kind, file.contents),
+		})
+	}
+
+	slices.SortFunc(next.Files, func(a, b ownedFile) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	if len(next.Files) == 0 {
+		if err := fs.Remove(input.manifestFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing manifest: %q: %w", input.manifestFile, err)
+		}
+
+		return nil
+	}
+
+	return writeManifest(fs, input.manifestFile, next)
+}
+
+func readManifest(fs billy.Filesystem, manifestFile string) (manifest, bool, error) {
+	contents, err := billyutil.ReadFile(fs, manifestFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest{}, false, nil
+	}
+	if err != nil {
+		return manifest{}, false, fmt.Errorf("reading manifest: %q: %w", manifestFile, err)
+	}
+
+	var m manifest
+	if err := yaml.Unmarshal(contents, &m); err != nil {
+		return manifest{}, false, fmt.Errorf("reading manifest: %q: %w", manifestFile, err)
+	}
+
+	for idx := range m.Files {
+		m.Files[idx].Path = filepath.Clean(m.Files[idx].Path)
+	}
+
+	return m, true, nil
+}
+
+func writeManifest(fs billy.Filesystem, manifestFile string, m manifest) error {
+	if err := fs.MkdirAll(filepath.Dir(manifestFile), os.ModePerm); err != nil {
+		return fmt.Errorf("creating directory: %q: %w", filepath.Dir(manifestFile), err)
+	}
+
+	fileBytes, err := util.YamlMarshalWithOptions(m)
+	if err != nil {
+		return fmt.Errorf("marshalling manifest to yaml: %w", err)
+	}
+
+	if err := billyutil.WriteFile(fs, manifestFile, fileBytes, os.ModePerm); err != nil {
+		return fmt.Errorf("writing manifest to file: %q: %w", manifestFile, err)
+	}
+
+	return nil
+}
+
+// hashContents checksums the contents of a generated file.
+// The targetRevision of applications is left out, as the deploy jobs promote to it.
+func hashContents(kind fileKind, contents []byte) string {
+	if kind == fileKindApplication {
+		if app, err := argocd.ReadApplication(contents); err == nil {
+			if app.Spec.Source != nil {
+				app.Spec.Source.TargetRevision = ""
+			}
+			for idx := range app.Spec.Sources {
+				app.Spec.Sources[idx].TargetRevision = ""
+			}
+
+			if normalized, err := util.YamlMarshalWithOptions(app); err == nil {
+				contents = normalized
+			}
+		}
+	}
+
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
+	billyutil "github.com/go-git/go-billy/v6/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("writeGeneratedFiles", func() {
+	const (
+		manifestFile = ".alveus/manifests/podinfo.yaml"
+		workflowA    = ".github/workflows/alveus-podinfo-a.yml"
+		workflowB    = ".github/workflows/alveus-podinfo-b.yml"
+		handWritten  = ".github/workflows/alveus-hand-written.yml"
+		application  = ".alveus/applications/podinfo-prod.yaml"
+	)
+
+	var (
+		fs       billy.Filesystem
+		warnings *bytes.Buffer
+		input    generatedFilesInput
+	)
+
+	applicationAt := func(revision string) []byte {
+		return []byte("apiVersion: argoproj.io/v1alpha1\nkind: Application\nmetadata:\n  name: podinfo-prod\nspec:\n  source:\n    targetRevision: " + revision + "\n")
+	}
+
+	write := func() {
+		Expect(writeGeneratedFiles(fs, input)).To(Succeed())
+	}
+
+	exists := func(path string) bool {
+		_, err := fs.Stat(path)
+		if os.IsNotExist(err) {
+			return false
+		}
+		Expect(err).NotTo(HaveOccurred())
+		return true
+	}
+
+	writeFile := func(path string, contents []byte) {
+		Expect(billyutil.WriteFile(fs, path, contents, os.ModePerm)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		fs = memfs.New()
+		warnings = &bytes.Buffer{}
+		writeFile(handWritten, []byte("name: hand-written\n"))
+
+		input = generatedFilesInput{
+			manifestFile: manifestFile,
+			service:      "podinfo",
+			files: []generatedFile{
+				{path: workflowA, kind: fileKindWorkflow, contents: []byte("name: a\n")},
+				{path: workflowB, kind: fileKindWorkflow, contents: []byte("name: b\n")},
+				{path: "./" + application, kind: fileKindApplication, contents: applicationAt("HEAD")},
+			},
+			warnings: warnings,
+		}
+		write()
+	})
+
+	It("should record the files it generated", func() {
+		m, found, err := readManifest(fs, manifestFile)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(m.Service).To(Equal("podinfo"))
+		Expect(m.Files).To(HaveLen(3))
+		Expect(m.Files[0].Path).To(Equal(application))
+		Expect(m.Files[0].Kind).To(Equal(fileKindApplication))
+		Expect(m.Files[1].Path).To(Equal(workflowA))
+		Expect(m.Files[2].Path).To(Equal(workflowB))
+		Expect(m.Files[2].SHA256).To(HaveLen(64))
+	})
+
+	It("should leave files it does not own alone", func() {
+		Expect(exists(handWritten)).To(BeTrue())
+		Expect(warnings.String()).To(BeEmpty())
+	})
+
+	When("a file is no longer generated", func() {
+		BeforeEach(func() {
+			input.files = input.files[:1]
+		})
+
+		It("should remove it", func() {
+			write()
+			Expect(exists(workflowA)).To(BeTrue())
+			Expect(exists(workflowB)).To(BeFalse())
+			Expect(exists(application)).To(BeFalse())
+			Expect(exists(handWritten)).To(BeTrue())
+
+			m, _, err := readManifest(fs, manifestFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Files).To(HaveLen(1))
+		})
+
+		It("should remove applications the deploy jobs promoted", func() {
+			writeFile(application, applicationAt("abc123"))
+			write()
+			Expect(exists(application)).To(BeFalse())
+			Expect(warnings.String()).To(BeEmpty())
+		})
+
+		It("should keep it when it was modified since generated", func() {
+			writeFile(workflowB, []byte("name: b\non: push\n"))
+			write()
+			Expect(exists(workflowB)).To(BeTrue())
+			Expect(warnings.String()).To(Equal("warning: not removing .github/workflows/alveus-podinfo-b.yml: modified since it was generated\n"))
+
+			m, _, err := readManifest(fs, manifestFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Files).To(HaveLen(1))
+		})
+
+		It("should keep the files of kinds which are not generated this time", func() {
+			input.unmanaged = []fileKind{fileKindApplication}
+			write()
+			Expect(exists(application)).To(BeTrue())
+
+			m, _, err := readManifest(fs, manifestFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(m.Files).To(HaveLen(2))
+			Expect(m.Files[0].Path).To(Equal(application))
+		})
+	})
+
+	When("a file it owns was modified since generated", func() {
+		BeforeEach(func() {
+			writeFile(workflowA, []byte("name: a\non: push\n"))
+		})
+
+		It("should overwrite it, with a warning", func() {
+			write()
+			contents, err := billyutil.ReadFile(fs, workflowA)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("name: a\n"))
+			Expect(warnings.String()).To(Equal("warning: overwriting .github/workflows/alveus-podinfo-a.yml: modified since it was generated\n"))
+		})
+	})
+
+	When("a file it does not own is generated", func() {
+		BeforeEach(func() {
+			input.files = append(input.files, generatedFile{path: handWritten, kind: fileKindWorkflow, contents: []byte("name: generated\n")})
+		})
+
+		It("should overwrite it, with a warning", func() {
+			write()
+			Expect(warnings.String()).To(Equal("warning: overwriting .github/workflows/alveus-hand-written.yml: not generated for service podinfo\n"))
+		})
+	})
+})
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cakehappens/gocto"
+	"github.com/go-git/go-billy/v6"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/constants"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/github"
+)
+
+// rolloutOutputs is the rollout workflow, along with the workflows triggering it, and the services it deploys.
+type rolloutOutputs struct {
+	// workflows are the trigger workflows, and the rollout workflow last.
+	workflows []gocto.Workflow
+	// schedule is the schedule of every service deployed, as the rollout workflow is triggered in their place.
+	schedule []v1alpha1.Schedule
+	services []string
+}
+
+// resolveServiceDependencies ignores the dependencies which are not generated, warning about them,
+// so that a service can be generated on its own, as if it did not depend on them.
+// It drops the services which are part of a dependency cycle, returning their errors.
+func resolveServiceDependencies(generated []serviceOutputs, warnings io.Writer) ([]serviceOutputs, []error) {
+	if warnings == nil {
+		warnings = io.Discard
+	}
+
+	for idx, svc := range generated {
+		var dependsOn []string
+		for _, dependency := range svc.service.DependsOn {
+			if !slices.ContainsFunc(generated, func(other serviceOutputs) bool {
+				return other.service.Name == dependency
+			}) {
+				_, _ = fmt.Fprintf(warnings, "warning: %s: service %s: depends on service %s, which is not generated, so it is not waited for\n",
+					svc.source, svc.service.Name, dependency)
+				continue
+			}
+
+			dependsOn = append(dependsOn, dependency)
+		}
+		generated[idx].service.DependsOn = dependsOn
+	}
+
+	var errs []error
+
+	for {
+		cycle := findServiceCycle(generated)
+		if len(cycle) == 0 {
+			return generated, errs
+		}
+
+		err := fmt.Errorf("service dependency cycle: %s", strings.Join(cycle, " -> "))
+
+		var kept []serviceOutputs
+		for _, svc := range generated {
+			// the cycle ends with the service it starts with
+			if slices.Contains(cycle[:len(cycle)-1], svc.service.Name) {
+				errs = append(errs, fmt.Errorf("%s: service %s: %w", svc.source, svc.service.Name, err))
+				continue
+			}
+
+			kept = append(kept, svc)
+		}
+		generated = kept
+	}
+}
+
+// findServiceCycle returns the path of the first dependency cycle between the services, if any.
+// Every dependency must be one of the services.
+func findServiceCycle(generated []serviceOutputs) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	dependsOn := make(map[string][]string, len(generated))
+	for _, svc := range generated {
+		dependsOn[svc.service.Name] = svc.service.DependsOn
+	}
+
+	state := make(map[string]int, len(generated))
+
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			start := slices.Index(path, name)
+			return append(slices.Clone(path[start:]), name)
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dependency := range dependsOn[name] {
+			if cycle := visit(dependency); cycle != nil {
+				return cycle
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, svc := range generated {
+		if cycle := visit(svc.service.Name); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// generateRollout generates the rollout workflow of the services which depend on each other, or are depended upon,
+// moving their push triggers from their top-level workflows to the workflows triggering it.
+func generateRollout(generated []serviceOutputs, claims outputClaims, outputs generateOutputs) (*rolloutOutputs, error) {
+	var members []github.RolloutMember
+	var memberIdxs []int
+	for idx, svc := range generated {
+		dependedOn := slices.ContainsFunc(generated, func(other serviceOutputs) bool {
+			return slices.Contains(other.service.DependsOn, svc.service.Name)
+		})
+		if len(svc.service.DependsOn) == 0 && !dependedOn {
+			continue
+		}
+
+		members = append(members, github.RolloutMember{
+			Service: svc.service,
+			Top:     svc.top(),
+		})
+		memberIdxs = append(memberIdxs, idx)
+	}
+
+	if len(members) == 0 {
+		return nil, nil
+	}
+
+	wfs, err := github.NewRolloutWorkflows(members)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := claims.claim("the "+constants.RolloutWorkflowName+" workflow", constants.RolloutWorkflowName, nil, wfs, outputs); err != nil {
+		return nil, err
+	}
+
+	rollout := &rolloutOutputs{workflows: wfs}
+	for _, idx := range memberIdxs {
+		for _, schedule := range generated[idx].schedule {
+			if !slices.Contains(rollout.schedule, schedule) {
+				rollout.schedule = append(rollout.schedule, schedule)
+			}
+		}
+
+		generated[idx] = generated[idx].rolledOut(true)
+		rollout.services = append(rollout.services, generated[idx].service.Name)
+	}
+
+	return rollout, nil
+}
+
+// top returns the top-level workflow of the service, which github.NewWorkflows returns last.
+func (o serviceOutputs) top() gocto.Workflow {
+	return o.wfs[len(o.wfs)-1]
+}
+
+// rolledOut moves the push trigger and schedule of the service to the rollout workflow when it deploys the service,
+// or gives them back to its top-level workflow otherwise.
+func (o serviceOutputs) rolledOut(rolledOut bool) serviceOutputs {
+	wfs := slices.Clone(o.wfs)
+	if rolledOut {
+		wfs[len(wfs)-1].On.Push = nil
+		o.schedule = nil
+	} else {
+		wfs[len(wfs)-1].On.Push = o.service.Github.On.Push
+		o.schedule = o.service.Schedule
+	}
+	o.wfs = wfs
+
+	return o
+}
+
+// keptRollout returns the services deployed by the rollout workflow already written, when only some of them
+// are generated, in which case the rollout workflow is left as is, since the services which are not generated
+// would no longer be deployed by it.
+func (o generateOutputs) keptRollout(fs billy.Filesystem, generated generation) ([]string, bool, error) {
+	previous, _, err := readManifest(fs, filepath.Join(o.manifestPath, constants.RolloutWorkflowName+".yaml"))
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, name := range previous.Services {
+		if !slices.ContainsFunc(generated.services, func(svc serviceOutputs) bool {
+			return svc.service.Name == name
+		}) {
+			return previous.Services, true, nil
+		}
+	}
+
+	return nil, false, nil
+}
+
+// writeRollout writes the rollout workflow, or removes it once the services it deployed no longer depend on each other.
+func (o generateOutputs) writeRollout(fs billy.Filesystem, generated generation) error {
+	input := generatedFilesInput{
+		manifestFile: filepath.Join(o.manifestPath, constants.RolloutWorkflowName+".yaml"),
+		service:      constants.RolloutWorkflowName,
+		warnings:     o.warnings,
+	}
+
+	_, found, err := readManifest(fs, input.manifestFile)
+	if err != nil {
+		return err
+	}
+
+	if generated.rollout == nil {
+		if !found {
+			return nil
+		}
+	} else {
+		files, err := renderWorkflows(o.workflowOutputPath, generated.rollout.workflows, generated.rollout.schedule)
+		if err != nil {
+			return fmt.Errorf("rendering workflows: %w", err)
+		}
+
+		input.files = files
+		input.services = generated.rollout.services
+	}
+
+	return writeGeneratedFiles(fs, input)
+}
@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
+	billyutil "github.com/go-git/go-billy/v6/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+)
+
+var _ = Describe("ordering services", func() {
+	var (
+		definitions []serviceDefinition
+		outputs     generateOutputs
+
+		generated generation
+		errs      []error
+	)
+
+	definitionOf := func(name string, dependsOn ...string) serviceDefinition {
+		service, err := v1alpha1.NewFromYaml([]byte(fmt.Sprintf(`
+name: %[1]s
+destinationNamespace: %[1]s
+dependsOn: [%[2]s]
+argoCD:
+  source:
+    path: %[1]s
+schedule:
+- cron: "0 2 * * *"
+github:
+  "on":
+    push:
+      branches: [main]
+      paths: [%[1]s/**]
+destinationGroups:
+- name: staging
+  destinations:
+  - name: in-cluster
+- name: prod
+  destinations:
+  - name: in-cluster
+`, name, strings.Join(dependsOn, ", "))))
+		Expect(err).NotTo(HaveOccurred())
+		return serviceDefinition{source: name + ".yaml", service: service}
+	}
+
+	generatedService := func(name string) serviceOutputs {
+		for _, svc := range generated.services {
+			if svc.service.Name == name {
+				return svc
+			}
+		}
+		Fail("service not generated: " + name)
+		return serviceOutputs{}
+	}
+
+	BeforeEach(func() {
+		outputs = generateOutputs{
+			repoURL:                "https://github.com/example/monorepo.git",
+			applicationOutputPath:  ".alveus/applications",
+			workflowOutputPath:     ".github/workflows",
+			environmentsReportPath: ".alveus/environments",
+			manifestPath:           ".alveus/manifests",
+			writeApps:              true,
+		}
+
+		definitions = []serviceDefinition{
+			definitionOf("frontend", "api"),
+			definitionOf("api", "migrator"),
+			definitionOf("migrator"),
+			definitionOf("unrelated"),
+		}
+	})
+
+	JustBeforeEach(func() {
+		generated, errs = generateServices(definitions, outputs)
+	})
+
+	It("should roll out each group after the same group of the services depended on", func() {
+		Expect(errs).To(BeEmpty())
+		Expect(generated.rollout).NotTo(BeNil())
+		Expect(generated.rollout.services).To(Equal([]string{"frontend", "api", "migrator"}))
+
+		wfs := generated.rollout.workflows
+		wf := wfs[len(wfs)-1]
+		Expect(wf.GetFilename()).To(Equal("alveus-rollout.yml"))
+		Expect(wf.Jobs).To(HaveLen(7))
+		Expect(wf.Jobs["migrator-staging"].Needs).To(Equal([]string{"resolve-revision"}))
+		Expect(wf.Jobs["api-staging"].Needs).To(Equal([]string{"resolve-revision", "migrator-staging"}))
+		Expect(wf.Jobs["api-prod"].Needs).To(Equal([]string{"resolve-revision", "api-staging", "migrator-prod"}))
+		Expect(wf.Jobs["frontend-prod"].Needs).To(Equal([]string{"resolve-revision", "frontend-staging", "api-prod"}))
+		Expect(wf.Jobs["frontend-prod"].Uses).To(Equal("./.github/workflows/alveus-frontend-prod.yml"))
+		Expect(wf.Jobs["frontend-prod"].With).To(HaveKeyWithValue("revision", "${{ needs.resolve-revision.outputs.revision }}"))
+	})
+
+	It("should trigger the rollout workflow on the push trigger of each service it deploys", func() {
+		Expect(errs).To(BeEmpty())
+
+		wfs := generated.rollout.workflows
+		Expect(wfs).To(HaveLen(4))
+		Expect(wfs[len(wfs)-1].On.Push).To(BeNil())
+		Expect(wfs[len(wfs)-1].On.Call.Inputs).To(HaveKey("revision"))
+
+		trigger := workflowNamed(wfs, "alveus-rollout-migrator.yml")
+		Expect(trigger.On.Push.Branches).To(Equal([]string{"main"}))
+		Expect(trigger.On.Push.Paths).To(Equal([]string{"migrator/**"}))
+		Expect(trigger.Jobs).To(HaveLen(1))
+		Expect(trigger.Jobs["rollout"].Uses).To(Equal("./.github/workflows/alveus-rollout.yml"))
+
+		Expect(generatedService("api").top().On.Push).To(BeNil())
+		Expect(generatedService("unrelated").top().On.Push).NotTo(BeNil())
+	})
+
+	When("the push triggers of the services differ", func() {
+		BeforeEach(func() {
+			definitions[1].service.Github.On.Push.Branches = []string{"release"}
+		})
+
+		It("should keep the filters of each service apart", func() {
+			Expect(errs).To(BeEmpty())
+
+			api := workflowNamed(generated.rollout.workflows, "alveus-rollout-api.yml").On.Push
+			Expect(api.Branches).To(Equal([]string{"release"}))
+			Expect(api.Paths).To(Equal([]string{"api/**"}))
+
+			migrator := workflowNamed(generated.rollout.workflows, "alveus-rollout-migrator.yml").On.Push
+			Expect(migrator.Branches).To(Equal([]string{"main"}))
+			Expect(migrator.Paths).To(Equal([]string{"migrator/**"}))
+		})
+	})
+
+	It("should move the schedule of the services rolled out to the rollout workflow", func() {
+		Expect(errs).To(BeEmpty())
+		Expect(generated.rollout.schedule).To(Equal([]v1alpha1.Schedule{{Cron: "0 2 * * *"}}))
+		Expect(generatedService("api").schedule).To(BeEmpty())
+		Expect(generatedService("unrelated").schedule).To(Equal([]v1alpha1.Schedule{{Cron: "0 2 * * *"}}))
+	})
+
+	When("a service depends on a service which is not generated", func() {
+		var warnings *bytes.Buffer
+
+		BeforeEach(func() {
+			warnings = &bytes.Buffer{}
+			outputs.warnings = warnings
+			definitions = definitions[1:2]
+		})
+
+		It("should generate it on its own, with its push trigger", func() {
+			Expect(errs).To(BeEmpty())
+			Expect(warnings.String()).To(Equal("warning: api.yaml: service api: depends on service migrator, which is not generated, so it is not waited for\n"))
+			Expect(generated.rollout).To(BeNil())
+			Expect(generatedService("api").top().On.Push).NotTo(BeNil())
+		})
+
+		When("other services depend on it", func() {
+			BeforeEach(func() {
+				definitions = []serviceDefinition{definitionOf("frontend", "api"), definitionOf("api", "migrator")}
+			})
+
+			It("should roll out the services which are generated", func() {
+				Expect(errs).To(BeEmpty())
+				Expect(generated.rollout).NotTo(BeNil())
+				Expect(generated.rollout.services).To(Equal([]string{"frontend", "api"}))
+			})
+		})
+	})
+
+	When("services depend on each other", func() {
+		BeforeEach(func() {
+			definitions[2] = definitionOf("migrator", "frontend")
+		})
+
+		It("should drop the services of the cycle", func() {
+			Expect(errs).To(HaveLen(3))
+			Expect(errs[0]).To(MatchError("frontend.yaml: service frontend: service dependency cycle: frontend -> api -> migrator -> frontend"))
+			Expect(generated.services).To(HaveLen(1))
+			Expect(generatedService("unrelated").service.DependsOn).To(BeEmpty())
+			Expect(generated.rollout).To(BeNil())
+		})
+	})
+
+	When("a group of a service depended on skips its promotions outside of its deploy windows", func() {
+		BeforeEach(func() {
+			maxWaitMinutes := 60
+			definitions[2].service.DestinationGroups[1].DeployWindows = &v1alpha1.DeployWindows{
+				Blackouts:      []v1alpha1.TimeWindow{{Cron: "0 0 24 12 *", Duration: "48h"}},
+				Action:         v1alpha1.DeployWindowActionSkip,
+				MaxWaitMinutes: &maxWaitMinutes,
+			}
+		})
+
+		It("should err", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(MatchError("rollout workflow: service api: destination group prod waits for the group of service migrator, " +
+				"so it must wait or fail outside of its deploy windows, rather than skip"))
+			Expect(generated.rollout).To(BeNil())
+		})
+	})
+
+	When("a service is named after the rollout workflow", func() {
+		BeforeEach(func() {
+			definitions = append(definitions, definitionOf("rollout"))
+		})
+
+		It("should err", func() {
+			Expect(errs).To(HaveLen(1))
+			Expect(errs[0]).To(MatchError("rollout.yaml: service rollout: service name is reserved: rollout"))
+		})
+	})
+
+	Context("writing the rollout workflow", func() {
+		var fs billy.Filesystem
+
+		BeforeEach(func() {
+			fs = memfs.New()
+		})
+
+		JustBeforeEach(func() {
+			Expect(outputs.write(fs, generated)).To(Succeed())
+			_, err := fs.Stat(".github/workflows/alveus-rollout.yml")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should remove it once the services no longer depend on each other", func() {
+			for idx := range definitions {
+				definitions[idx].service.DependsOn = nil
+			}
+			generated, errs = generateServices(definitions, outputs)
+			Expect(errs).To(BeEmpty())
+			Expect(outputs.write(fs, generated)).To(Succeed())
+
+			_, err := fs.Stat(".github/workflows/alveus-rollout.yml")
+			Expect(err).To(MatchError(ContainSubstring("not exist")))
+			_, err = fs.Stat(".alveus/manifests/rollout.yaml")
+			Expect(err).To(MatchError(ContainSubstring("not exist")))
+		})
+
+		topWorkflowOf := func(name string) string {
+			contents, err := billyutil.ReadFile(fs, ".github/workflows/alveus-"+name+".yml")
+			Expect(err).NotTo(HaveOccurred())
+			return string(contents)
+		}
+
+		It("should keep it when only some of its services are generated", func() {
+			generated, errs = generateServices(definitions[3:], outputs)
+			Expect(errs).To(BeEmpty())
+			Expect(outputs.write(fs, generated)).To(Succeed())
+
+			_, err := fs.Stat(".github/workflows/alveus-rollout.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(topWorkflowOf("unrelated")).To(ContainSubstring("push:"))
+		})
+
+		It("should keep deploying a service it deploys through it, when the service is generated on its own", func() {
+			generated, errs = generateServices(definitions[1:2], outputs)
+			Expect(errs).To(BeEmpty())
+			Expect(generated.rollout).To(BeNil())
+			Expect(outputs.write(fs, generated)).To(Succeed())
+
+			_, err := fs.Stat(".github/workflows/alveus-rollout-api.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(topWorkflowOf("api")).NotTo(ContainSubstring("push:"))
+			Expect(topWorkflowOf("api")).NotTo(ContainSubstring("schedule:"))
+		})
+
+		It("should give a service it does not deploy its push trigger, when it is generated with some of its services", func() {
+			definitions = []serviceDefinition{definitionOf("api", "migrator"), definitionOf("unrelated", "api")}
+			generated, errs = generateServices(definitions, outputs)
+			Expect(errs).To(BeEmpty())
+			Expect(generated.rollout.services).To(Equal([]string{"api", "unrelated"}))
+			Expect(outputs.write(fs, generated)).To(Succeed())
+
+			Expect(topWorkflowOf("api")).NotTo(ContainSubstring("push:"))
+			Expect(topWorkflowOf("unrelated")).To(ContainSubstring("push:"))
+			Expect(topWorkflowOf("unrelated")).To(ContainSubstring("schedule:"))
+		})
+
+		It("should keep it when only some of its services are generated, even when they depend on each other", func() {
+			before, err := billyutil.ReadFile(fs, ".github/workflows/alveus-rollout.yml")
+			Expect(err).NotTo(HaveOccurred())
+
+			generated, errs = generateServices(definitions[:2], outputs)
+			Expect(errs).To(BeEmpty())
+			Expect(generated.rollout).NotTo(BeNil())
+			Expect(outputs.write(fs, generated)).To(Succeed())
+
+			after, err := billyutil.ReadFile(fs, ".github/workflows/alveus-rollout.yml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(after).To(Equal(before))
+			_, err = fs.Stat(".github/workflows/alveus-rollout-migrator.yml")
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
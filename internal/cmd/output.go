@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+const (
+	outputFormatTable = "table"
+	outputFormatJSON  = "json"
+	outputFormatYAML  = "yaml"
+)
+
+// writeOutput writes val in the requested format, using writeTable to render it as a table.
+func writeOutput(w io.Writer, format string, val any, writeTable func(tw *tabwriter.Writer)) error {
+	switch format {
+	case outputFormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		writeTable(tw)
+		return tw.Flush()
+	case outputFormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(val)
+	case outputFormatYAML:
+		fileBytes, err := util.YamlMarshalWithOptions(val)
+		if err != nil {
+			return fmt.Errorf("marshalling to yaml: %w", err)
+		}
+		_, err = w.Write(fileBytes)
+		return err
+	default:
+		return fmt.Errorf("unsupported output format: %q, expected one of: %s, %s, %s",
+			format, outputFormatTable, outputFormatJSON, outputFormatYAML)
+	}
+}
+
+// formatRevisions renders the promoted revisions of an application, e.g. "abc123 chart:1.2.3".
+func formatRevisions(revision, chartVersion string) string {
+	var parts []string
+	if revision != "" {
+		parts = append(parts, revision)
+	}
+
+	if chartVersion != "" {
+		parts = append(parts, "chart:"+chartVersion)
+	}
+
+	return util.Join(" ", parts...)
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
+	billyutil "github.com/go-git/go-billy/v6/util"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+type changeKind string
+
+const (
+	changeCreated  changeKind = "created"
+	changeModified changeKind = "modified"
+	changeDeleted  changeKind = "deleted"
+)
+
+type fileChange struct {
+	Path   string
+	Kind   changeKind
+	Before []byte
+	After  []byte
+}
+
+// planChanges runs write against an in-memory copy of the given directories,
+// and returns how the files of those directories would change, ordered by path.
+func planChanges(disk billy.Filesystem, dirs []string, write func(fs billy.Filesystem) error) ([]fileChange, error) {
+	before, err := readFiles(disk, dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	planned := memfs.New()
+	for path, contents := range before {
+		if err := billyutil.WriteFile(planned, path, contents, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("copying file: %q: %w", path, err)
+		}
+	}
+
+	if err := write(planned); err != nil {
+		return nil, err
+	}
+
+	after, err := readFiles(planned, dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []fileChange
+	for path, contents := range after {
+		previous, existed := before[path]
+		switch {
+		case !existed:
+			changes = append(changes, fileChange{Path: path, Kind: changeCreated, After: contents})
+		case !bytes.Equal(previous, contents):
+			changes = append(changes, fileChange{Path: path, Kind: changeModified, Before: previous, After: contents})
+		}
+	}
+
+	for path, contents := range before {
+		if _, exists := after[path]; !exists {
+			changes = append(changes, fileChange{Path: path, Kind: changeDeleted, Before: contents})
+		}
+	}
+
+	slices.SortFunc(changes, func(a, b fileChange) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	return changes, nil
+}
+
+// readFiles reads every file under the given directories, keyed by (cleaned) path.
+// Missing directories are skipped.
+func readFiles(fsys billy.Filesystem, dirs []string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+
+		err := billyutil.Walk(fsys, dir, func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+
+			if info.IsDir() {
+				return nil
+			}
+
+			contents, err := billyutil.ReadFile(fsys, path)
+			if err != nil {
+				return fmt.Errorf("reading file: %q: %w", path, err)
+			}
+			files[filepath.Clean(path)] = contents
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("reading directory: %q: %w", dir, err)
+		}
+	}
+
+	return files, nil
+}
+
+// writeChanges lists the changed files, followed by their unified diff.
+func writeChanges(w io.Writer, changes []fileChange) error {
+	if len(changes) == 0 {
+		_, err := fmt.Fprintln(w, "no changes")
+		return err
+	}
+
+	for _, change := range changes {
+		if _, err := fmt.Fprintf(w, "%s: %s\n", change.Kind, change.Path); err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes {
+		fromFile, toFile := "a/"+change.Path, "b/"+change.Path
+		switch change.Kind {
+		case changeCreated:
+			fromFile = "/dev/null"
+		case changeDeleted:
+			toFile = "/dev/null"
+		}
+
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        splitLines(change.Before),
+			B:        splitLines(change.After),
+			FromFile: fromFile,
+			ToFile:   toFile,
+			Context:  3,
+		})
+		if err != nil {
+			return fmt.Errorf("diffing file: %q: %w", change.Path, err)
+		}
+
+		if _, err := fmt.Fprintf(w, "\n%s", diff); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitLines splits contents into lines, keeping their line endings, as the unified diff expects.
+func splitLines(contents []byte) []string {
+	lines := strings.SplitAfter(string(contents), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
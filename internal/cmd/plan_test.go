@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
+	billyutil "github.com/go-git/go-billy/v6/util"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("planChanges", func() {
+	var (
+		disk  billy.Filesystem
+		write func(fs billy.Filesystem) error
+
+		changes   []fileChange
+		actualErr error
+	)
+
+	writeFile := func(fs billy.Filesystem, path, contents string) {
+		Expect(billyutil.WriteFile(fs, path, []byte(contents), os.ModePerm)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		disk = memfs.New()
+		writeFile(disk, "out/kept.yaml", "a: 1\n")
+		writeFile(disk, "out/modified.yaml", "a: 1\nb: 2\n")
+		writeFile(disk, "out/deleted.yaml", "a: 1\n")
+		writeFile(disk, "elsewhere/untouched.yaml", "a: 1\n")
+
+		write = func(fs billy.Filesystem) error {
+			writeFile(fs, "out/modified.yaml", "a: 1\nb: 3\n")
+			writeFile(fs, "out/created.yaml", "c: 1\n")
+			return fs.Remove("out/deleted.yaml")
+		}
+	})
+
+	JustBeforeEach(func() {
+		changes, actualErr = planChanges(disk, []string{"./out", "missing"}, write)
+	})
+
+	It("reports the created, modified and deleted files, ordered by path", func() {
+		Expect(actualErr).NotTo(HaveOccurred())
+		Expect(changes).To(Equal([]fileChange{
+			{Path: "out/created.yaml", Kind: changeCreated, After: []byte("c: 1\n")},
+			{Path: "out/deleted.yaml", Kind: changeDeleted, Before: []byte("a: 1\n")},
+			{Path: "out/modified.yaml", Kind: changeModified, Before: []byte("a: 1\nb: 2\n"), After: []byte("a: 1\nb: 3\n")},
+		}))
+	})
+
+	It("leaves the disk untouched", func() {
+		Expect(actualErr).NotTo(HaveOccurred())
+
+		contents, err := billyutil.ReadFile(disk, "out/modified.yaml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(Equal("a: 1\nb: 2\n"))
+
+		_, err = disk.Stat("out/deleted.yaml")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = disk.Stat("out/created.yaml")
+		Expect(err).To(MatchError(os.ErrNotExist))
+	})
+
+	When("writing fails", func() {
+		BeforeEach(func() {
+			write = func(fs billy.Filesystem) error {
+				return os.ErrPermission
+			}
+		})
+
+		It("returns the error", func() {
+			Expect(actualErr).To(MatchError(os.ErrPermission))
+		})
+	})
+
+	When("nothing changes", func() {
+		BeforeEach(func() {
+			write = func(fs billy.Filesystem) error {
+				return nil
+			}
+		})
+
+		It("reports no changes", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(changes).To(BeEmpty())
+		})
+	})
+})
+
+var _ = Describe("writeChanges", func() {
+	It("lists the changes, followed by their diff", func() {
+		var out bytes.Buffer
+		Expect(writeChanges(&out, []fileChange{
+			{Path: "out/created.yaml", Kind: changeCreated, After: []byte("c: 1\n")},
+			{Path: "out/modified.yaml", Kind: changeModified, Before: []byte("a: 1\nb: 2\n"), After: []byte("a: 1\nb: 3\n")},
+		})).To(Succeed())
+
+		Expect(out.String()).To(Equal("" +
+			"created: out/created.yaml\n" +
+			"modified: out/modified.yaml\n" +
+			"\n" +
+			"--- /dev/null\n" +
+			"+++ b/out/created.yaml\n" +
+			"@@ -0,0 +1 @@\n" +
+			"+c: 1\n" +
+			"\n" +
+			"--- a/out/modified.yaml\n" +
+			"+++ b/out/modified.yaml\n" +
+			"@@ -1,2 +1,2 @@\n" +
+			" a: 1\n" +
+			"-b: 2\n" +
+			"+b: 3\n",
+		))
+	})
+
+	It("reports when nothing changes", func() {
+		var out bytes.Buffer
+		Expect(writeChanges(&out, nil)).To(Succeed())
+		Expect(out.String()).To(Equal("no changes\n"))
+	})
+})
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/osfs"
+	billyutil "github.com/go-git/go-billy/v6/util"
+	"github.com/spf13/cobra"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+func NewRollbackCommand() *cobra.Command {
+	var applicationOutputPath string
+	var input rollbackInput
+
+	cmd := &cobra.Command{
+		Use:   "rollback [service-file]",
+		Short: "pin the applications of a destination group (or a single destination) to a previous revision",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			service, err := readService(args)
+			if err != nil {
+				return err
+			}
+
+			input.files, err = readApplicationFiles(service, applicationOutputPath)
+			if err != nil {
+				return err
+			}
+
+			rolledBack, err := rollbackApps(osfs.New("."), input)
+			if err != nil {
+				return err
+			}
+
+			for _, file := range rolledBack {
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "rolled back: %s\n", file)
+			}
+
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&applicationOutputPath, "application-output-path", "./.alveus/applications", "path to where the ArgoCD application resources were written")
+	f.StringVarP(&input.group, "group", "g", "", "destination group to roll back")
+	f.StringVarP(&input.destination, "destination", "d", "", "destination to roll back, defaults to every destination of the group")
+	f.StringVar(&input.revisions.Revision, "revision", "", "commit of the service repository to roll back to")
+	f.StringVar(&input.revisions.ChartVersion, "chart-version", "", "version to roll back charts from a helm repository to")
+
+	err := cmd.MarkFlagRequired("group")
+	if err != nil {
+		panic(err)
+	}
+	cmd.MarkFlagsOneRequired("revision", "chart-version")
+
+	return cmd
+}
+
+type rollbackInput struct {
+	files       []applicationFile
+	group       string
+	destination string
+	revisions   argocd.PromotedRevisions
+}
+
+// rollbackApps rewrites the promoted targetRevision(s) of the application files of the selected destinations,
+// returning the paths of the files rewritten.
+func rollbackApps(fs billy.Filesystem, input rollbackInput) ([]string, error) {
+	var selected []applicationFile
+	for _, file := range input.files {
+		if file.group != input.group {
+			continue
+		}
+
+		if input.destination != "" && v1alpha1.CoalesceSanitizeDestination(file.destination) != input.destination {
+			continue
+		}
+
+		selected = append(selected, file)
+	}
+
+	if len(selected) == 0 {
+		if input.destination != "" {
+			return nil, fmt.Errorf("destination group: %s: no destination: %s", input.group, input.destination)
+		}
+
+		return nil, fmt.Errorf("no destination group: %s", input.group)
+	}
+
+	var rolledBack []string
+	for _, file := range selected {
+		contents, err := billyutil.ReadFile(fs, file.path)
+		if err != nil {
+			return rolledBack, fmt.Errorf("reading application file: %q: %w", file.path, err)
+		}
+
+		app, err := argocd.ReadApplication(contents)
+		if err != nil {
+			return rolledBack, fmt.Errorf("reading application file: %q: %w", file.path, err)
+		}
+
+		if err := argocd.SetPromotedRevisions(&app, file.destination.ArgoCD, input.revisions); err != nil {
+			return rolledBack, fmt.Errorf("rolling back application file: %q: %w", file.path, err)
+		}
+
+		fileBytes, err := util.YamlMarshalWithOptions(app)
+		if err != nil {
+			return rolledBack, fmt.Errorf("marshalling application to yaml: %w", err)
+		}
+
+		if err := billyutil.WriteFile(fs, file.path, fileBytes, os.ModePerm); err != nil {
+			return rolledBack, fmt.Errorf("writing application to file: %q: %w", file.path, err)
+		}
+
+		rolledBack = append(rolledBack, file.path)
+	}
+
+	return rolledBack, nil
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
+	billyutil "github.com/go-git/go-billy/v6/util"
+	"github.com/lithammer/dedent"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/github"
+)
+
+var _ = Describe("rollbackApps", func() {
+	const basepath = ".alveus/applications"
+
+	var (
+		fs      billy.Filesystem
+		service v1alpha1.Service
+		input   rollbackInput
+
+		rolledBack []string
+		actualErr  error
+	)
+
+	targetRevisionOf := func(path string) string {
+		contents, err := billyutil.ReadFile(fs, path)
+		Expect(err).NotTo(HaveOccurred())
+		app, err := argocd.ReadApplication(contents)
+		Expect(err).NotTo(HaveOccurred())
+		return app.Spec.Source.TargetRevision
+	}
+
+	BeforeEach(func() {
+		var err error
+		service, err = v1alpha1.NewFromYaml([]byte(dedent.Dedent(`
+			name: podinfo
+			destinationNamespace: podinfo
+			argoCD:
+			  source:
+			    path: manifests
+			destinationGroups:
+			- name: staging
+			  destinations:
+			  - name: in-cluster
+			- name: prod
+			  destinations:
+			  - name: in-cluster
+			  - server: https://prod.example.com
+		`)))
+		Expect(err).NotTo(HaveOccurred())
+
+		appRepo, err := generateApps("https://github.com/example/podinfo.git", "abc123", basepath, service)
+		Expect(err).NotTo(HaveOccurred())
+
+		fs = memfs.New()
+		Expect(writeAppFixtures(fs, basepath, appRepo.Applications())).To(Succeed())
+
+		files, err := resolveApplicationFiles(service, appRepo)
+		Expect(err).NotTo(HaveOccurred())
+
+		input = rollbackInput{
+			files: files,
+			group: "prod",
+			revisions: argocd.PromotedRevisions{
+				Revision: "0ld",
+			},
+		}
+	})
+
+	JustBeforeEach(func() {
+		rolledBack, actualErr = rollbackApps(fs, input)
+	})
+
+	It("should roll back every destination of the group", func() {
+		Expect(actualErr).NotTo(HaveOccurred())
+		Expect(rolledBack).To(Equal([]string{
+			".alveus/applications/podinfo-prod-in-cluster.yaml",
+			".alveus/applications/podinfo-prod-prod-example-com.yaml",
+		}))
+		Expect(targetRevisionOf(".alveus/applications/podinfo-prod-in-cluster.yaml")).To(Equal("0ld"))
+		Expect(targetRevisionOf(".alveus/applications/podinfo-prod-prod-example-com.yaml")).To(Equal("0ld"))
+		Expect(targetRevisionOf(".alveus/applications/podinfo-staging-in-cluster.yaml")).To(Equal("abc123"))
+	})
+
+	When("a destination is selected", func() {
+		BeforeEach(func() {
+			input.destination = "prod-example-com"
+		})
+
+		It("should only roll back that destination", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(rolledBack).To(Equal([]string{".alveus/applications/podinfo-prod-prod-example-com.yaml"}))
+			Expect(targetRevisionOf(".alveus/applications/podinfo-prod-in-cluster.yaml")).To(Equal("abc123"))
+		})
+	})
+
+	When("the group does not exist", func() {
+		BeforeEach(func() {
+			input.group = "dev"
+		})
+
+		It("should err", func() {
+			Expect(actualErr).To(MatchError("no destination group: dev"))
+		})
+	})
+
+	When("an application file is missing", func() {
+		BeforeEach(func() {
+			Expect(fs.Remove(".alveus/applications/podinfo-prod-in-cluster.yaml")).To(Succeed())
+		})
+
+		It("should err", func() {
+			Expect(actualErr).To(MatchError(ContainSubstring(`reading application file: ".alveus/applications/podinfo-prod-in-cluster.yaml"`)))
+			Expect(actualErr).To(MatchError(os.ErrNotExist))
+		})
+	})
+
+	Context("the generated rollback workflow", func() {
+		It("should redeploy the selected destinations using their deployment workflows", func() {
+			appRepo, err := generateApps("https://github.com/example/podinfo.git", "HEAD", basepath, service)
+			Expect(err).NotTo(HaveOccurred())
+			wfs, err := github.NewWorkflows(service, appRepo)
+			Expect(err).NotTo(HaveOccurred())
+
+			wf := workflowNamed(wfs, "alveus-podinfo-rollback.yml")
+			Expect(wf.On.Dispatch.Inputs["group"].Options).To(Equal([]string{"staging", "prod"}))
+			Expect(wf.On.Dispatch.Inputs["destination"].Options).To(Equal([]string{"all", "in-cluster", "prod-example-com"}))
+			Expect(wf.Jobs).To(HaveLen(3))
+
+			job := wf.Jobs["prod-prod-example-com"]
+			Expect(job.Uses).To(Equal("./.github/workflows/alveus-podinfo-prod-prod-example-com.yml"))
+			Expect(job.If).To(Equal("inputs.group == 'prod' && (inputs.destination == 'all' || inputs.destination == 'prod-example-com')"))
+			Expect(job.With).To(HaveKeyWithValue("revision", "${{ inputs.revision }}"))
+		})
+
+		It("should fail when the jobs of two destinations collide", func() {
+			service, err := v1alpha1.NewFromYaml([]byte(dedent.Dedent(`
+				name: podinfo
+				destinationNamespace: podinfo
+				applicationNameUniquenessStrategy:
+				  usingManyNamespaces: true
+				argoCD:
+				  source:
+				    path: manifests
+				destinationGroups:
+				- name: a-b
+				  destinations:
+				  - name: c
+				    namespace: one
+				- name: a
+				  destinations:
+				  - name: b-c
+				    namespace: two
+			`)))
+			Expect(err).NotTo(HaveOccurred())
+
+			appRepo, err := generateApps("https://github.com/example/podinfo.git", "HEAD", basepath, service)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = github.NewWorkflows(service, appRepo)
+			Expect(err).To(MatchError(ContainSubstring(`rollback job "a-b-c" is also the job of destination group a-b: destination c`)))
+		})
+	})
+})
@@ -12,6 +12,10 @@ func NewRootCommand() *cobra.Command {
 
 	cmd.AddCommand(
 		NewGenerateCommand(),
+		NewRollbackCommand(),
+		NewStatusCommand(),
+		NewHistoryCommand(),
+		NewCheckCommand(),
 	)
 
 	return cmd
@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+// readService reads the service definition from the file named by the first argument, or from stdin.
+func readService(args []string) (v1alpha1.Service, error) {
+	var serviceBytes []byte
+	var err error
+
+	var serviceFile string
+	if len(args) > 0 {
+		serviceFile = args[0]
+	}
+
+	if serviceFile == "" || serviceFile == "-" {
+		serviceBytes, err = readStdin()
+		if err != nil {
+			return v1alpha1.Service{}, err
+		}
+	} else {
+		serviceBytes, err = os.ReadFile(serviceFile)
+		if err != nil {
+			return v1alpha1.Service{}, fmt.Errorf("reading from file: %s: %w", serviceFile, err)
+		}
+	}
+
+	service, err := v1alpha1.NewFromYaml(serviceBytes)
+	if err != nil {
+		return v1alpha1.Service{}, fmt.Errorf("constructing/validating service definition: %w", err)
+	}
+
+	return service, nil
+}
+
+func readStdin() ([]byte, error) {
+	stat, _ := os.Stdin.Stat()
+	if (stat.Mode() & os.ModeCharDevice) != 0 {
+		return nil, fmt.Errorf("stdin is from a terminal")
+	}
+
+	contents, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("reading stdin: %w", err)
+	}
+
+	return contents, nil
+}
+
+// serviceDefinition is a service read from a service file, or why it could not be read.
+type serviceDefinition struct {
+	// source names where the service was read from, i.e. the file, and the document of the file if it has several.
+	source  string
+	service v1alpha1.Service
+	err     error
+}
+
+// readServiceDefinitions reads the service definitions from the files named by the arguments, or from stdin.
+// An argument may be a file, a directory (its .yaml and .yml files, not recursively) or a glob,
+// and each file may hold several YAML documents, one service each.
+// Files which cannot be read, and services which are invalid, are returned with their error.
+func readServiceDefinitions(args []string) ([]serviceDefinition, error) {
+	if len(args) == 0 || (len(args) == 1 && args[0] == "-") {
+		contents, err := readStdin()
+		if err != nil {
+			return nil, err
+		}
+
+		return parseServiceDefinitions("stdin", contents), nil
+	}
+
+	var files []string
+	var definitions []serviceDefinition
+	for _, arg := range args {
+		matches, err := resolveServiceFiles(arg)
+		if err != nil {
+			definitions = append(definitions, serviceDefinition{source: arg, err: err})
+			continue
+		}
+
+		for _, file := range matches {
+			if slices.Contains(files, file) {
+				continue
+			}
+			files = append(files, file)
+
+			contents, err := os.ReadFile(file)
+			if err != nil {
+				definitions = append(definitions, serviceDefinition{source: file, err: fmt.Errorf("reading from file: %w", err)})
+				continue
+			}
+
+			definitions = append(definitions, parseServiceDefinitions(file, contents)...)
+		}
+	}
+
+	return definitions, nil
+}
+
+// resolveServiceFiles lists the service files an argument names.
+func resolveServiceFiles(arg string) ([]string, error) {
+	if strings.ContainsAny(arg, "*?[") {
+		matches, err := filepath.Glob(arg)
+		if err != nil {
+			return nil, fmt.Errorf("matching files: %w", err)
+		}
+
+		if len(matches) == 0 {
+			return nil, errors.New("no files match")
+		}
+
+		return matches, nil
+	}
+
+	info, err := os.Stat(arg)
+	if err != nil {
+		return nil, fmt.Errorf("reading from file: %w", err)
+	}
+
+	if !info.IsDir() {
+		return []string{arg}, nil
+	}
+
+	entries, err := os.ReadDir(arg)
+	if err != nil {
+		return nil, fmt.Errorf("reading directory: %w", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+
+		files = append(files, filepath.Join(arg, entry.Name()))
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no service files in directory")
+	}
+
+	return files, nil
+}
+
+var yamlDocumentSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// parseServiceDefinitions parses every (non-empty) YAML document of a service file.
+func parseServiceDefinitions(source string, contents []byte) []serviceDefinition {
+	var documents [][]byte
+	for _, document := range yamlDocumentSeparator.Split(string(contents), -1) {
+		if isEmptyYamlDocument(document) {
+			continue
+		}
+
+		documents = append(documents, []byte(document))
+	}
+
+	definitions := make([]serviceDefinition, 0, len(documents))
+	for idx, document := range documents {
+		definition := serviceDefinition{source: source}
+		if len(documents) > 1 {
+			definition.source = fmt.Sprintf("%s#%d", source, idx+1)
+		}
+
+		definition.service, definition.err = v1alpha1.NewFromYaml(document)
+		if definition.err != nil {
+			definition.err = fmt.Errorf("constructing/validating service definition: %w", definition.err)
+		}
+
+		definitions = append(definitions, definition)
+	}
+
+	return definitions
+}
+
+func isEmptyYamlDocument(document string) bool {
+	for _, line := range strings.Split(document, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// applicationFile is the application file of a single destination of a service.
+type applicationFile struct {
+	group       string
+	destination v1alpha1.Destination
+	path        string
+}
+
+// resolveApplicationFiles lists the application file of each destination of the service, in order,
+// i.e. the file generate writes the application to, or the applicationFilePath the deploy job promotes instead.
+func resolveApplicationFiles(service v1alpha1.Service, appRepo argocd.ApplicationRepository) ([]applicationFile, error) {
+	var files []applicationFile
+
+	for _, group := range service.DestinationGroups {
+		for _, dest := range group.Destinations {
+			generatedPath, _, err := appRepo.GetByDestination(service.Name, group.Name, dest)
+			if err != nil {
+				return nil, err
+			}
+
+			files = append(files, applicationFile{
+				group:       group.Name,
+				destination: dest,
+				path:        util.CoalesceStrings(dest.ArgoCD.ApplicationFilePath, generatedPath),
+			})
+		}
+	}
+
+	return files, nil
+}
+
+// applicationFilesRepoURL is the repository of the applications generated only to resolve their files,
+// which do not depend on it.
+const applicationFilesRepoURL = "https://github.com/alveus/unknown.git"
+
+// readApplicationFiles resolves the application files of the service, as generated to the basepath.
+func readApplicationFiles(service v1alpha1.Service, basepath string) ([]applicationFile, error) {
+	appRepo, err := generateApps(applicationFilesRepoURL, "HEAD", basepath, service)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolveApplicationFiles(service, appRepo)
+}
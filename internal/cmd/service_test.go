@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/lithammer/dedent"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+)
+
+var _ = Describe("readServiceDefinitions", func() {
+	var (
+		dir         string
+		args        []string
+		definitions []serviceDefinition
+		actualErr   error
+	)
+
+	serviceYaml := func(name string) string {
+		return "name: " + name + "\n" +
+			"destinationNamespace: " + name + "\n" +
+			"argoCD:\n  source:\n    path: manifests\n" +
+			"destinationGroups:\n- name: staging\n  destinations:\n  - name: in-cluster\n"
+	}
+
+	writeFile := func(name, contents string) {
+		Expect(os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, name), []byte(contents), os.ModePerm)).To(Succeed())
+	}
+
+	sources := func() []string {
+		var names []string
+		for _, definition := range definitions {
+			names = append(names, definition.source)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		writeFile("services/alpha.yaml", serviceYaml("alpha"))
+		writeFile("services/more.yml", serviceYaml("beta")+"---\n# nothing here\n---\n"+serviceYaml("gamma"))
+		writeFile("services/README.md", "not a service")
+		writeFile("services/nested/delta.yaml", serviceYaml("delta"))
+	})
+
+	JustBeforeEach(func() {
+		definitions, actualErr = readServiceDefinitions(args)
+	})
+
+	When("a directory is passed", func() {
+		BeforeEach(func() {
+			args = []string{filepath.Join(dir, "services")}
+		})
+
+		It("should read every document of its service files", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(sources()).To(Equal([]string{
+				filepath.Join(dir, "services/alpha.yaml"),
+				filepath.Join(dir, "services/more.yml#1"),
+				filepath.Join(dir, "services/more.yml#2"),
+			}))
+
+			for idx, name := range []string{"alpha", "beta", "gamma"} {
+				Expect(definitions[idx].err).NotTo(HaveOccurred())
+				Expect(definitions[idx].service.Name).To(Equal(name))
+			}
+		})
+	})
+
+	When("files overlap with a glob", func() {
+		BeforeEach(func() {
+			args = []string{filepath.Join(dir, "services/alpha.yaml"), filepath.Join(dir, "services/*/*.yaml"), filepath.Join(dir, "services/a*.yaml")}
+		})
+
+		It("should read each file once, in order", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(sources()).To(Equal([]string{
+				filepath.Join(dir, "services/alpha.yaml"),
+				filepath.Join(dir, "services/nested/delta.yaml"),
+			}))
+		})
+	})
+
+	When("a file is missing or invalid", func() {
+		BeforeEach(func() {
+			writeFile("invalid.yaml", "name: [")
+			args = []string{filepath.Join(dir, "missing.yaml"), filepath.Join(dir, "invalid.yaml"), filepath.Join(dir, "nothing/*.yaml"), filepath.Join(dir, "services/alpha.yaml")}
+		})
+
+		It("should return their errors along with the other services", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(definitions).To(HaveLen(4))
+			Expect(definitions[0].err).To(MatchError(os.ErrNotExist))
+			Expect(definitions[1].err).To(MatchError(ContainSubstring("constructing/validating service definition")))
+			Expect(definitions[2].source).To(Equal(filepath.Join(dir, "nothing/*.yaml")))
+			Expect(definitions[2].err).To(MatchError("no files match"))
+			Expect(definitions[3].err).NotTo(HaveOccurred())
+			Expect(definitions[3].service.Name).To(Equal("alpha"))
+		})
+	})
+})
+
+var _ = Describe("readApplicationFiles", func() {
+	It("should resolve the files generate writes the applications to", func() {
+		service, err := v1alpha1.NewFromYaml([]byte(dedent.Dedent(`
+			name: svc
+			destinationNamespace: svc
+			argoCD:
+			  source:
+			    path: manifests
+			destinationGroups:
+			- name: staging
+			  destinations:
+			  - name: us_east.1
+			  - name: in-cluster
+			    argoCD:
+			      applicationFilePath: apps/in-cluster.yaml
+		`)))
+		Expect(err).NotTo(HaveOccurred())
+
+		files, err := readApplicationFiles(service, ".alveus/applications")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(2))
+		Expect(files[0].path).To(Equal(".alveus/applications/svc-staging-us-east-1.yaml"))
+		Expect(files[1].path).To(Equal("apps/in-cluster.yaml"))
+	})
+})
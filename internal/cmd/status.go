@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/osfs"
+	billyutil "github.com/go-git/go-billy/v6/util"
+	"github.com/spf13/cobra"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/git"
+)
+
+func NewStatusCommand() *cobra.Command {
+	var applicationOutputPath string
+	var repositoryPath string
+	var outputFormat string
+
+	cmd := &cobra.Command{
+		Use:   "status [service-file]",
+		Short: "show the revision deployed to each destination",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			service, err := readService(args)
+			if err != nil {
+				return err
+			}
+
+			repo, err := git.Open(repositoryPath)
+			if err != nil {
+				return err
+			}
+
+			files, err := readApplicationFiles(service, applicationOutputPath)
+			if err != nil {
+				return err
+			}
+
+			statuses := destinationStatuses(osfs.New("."), repo, files)
+
+			return writeOutput(cmd.OutOrStdout(), outputFormat, statuses, func(tw *tabwriter.Writer) {
+				_, _ = fmt.Fprintln(tw, "GROUP\tDESTINATION\tAPPLICATION\tREVISION\tCOMMITTED\tSUBJECT")
+				for _, status := range statuses {
+					var committed, subject string
+					switch {
+					case status.Error != "":
+						subject = "error: " + status.Error
+					case status.Commit != nil:
+						committed = status.Commit.Date.Format("2006-01-02 15:04")
+						subject = status.Commit.Subject
+					}
+
+					_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+						status.Group, status.Destination, status.Application,
+						formatRevisions(status.TargetRevision, status.ChartVersion), committed, subject)
+				}
+			})
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&applicationOutputPath, "application-output-path", "./.alveus/applications", "path to where the ArgoCD application resources were written")
+	f.StringVar(&repositoryPath, "repository-path", ".", "path to the git repository of the service")
+	f.StringVarP(&outputFormat, "output", "o", outputFormatTable, "output format, one of: table, json, yaml")
+
+	return cmd
+}
+
+type destinationStatus struct {
+	Group          string      `json:"group"`
+	Destination    string      `json:"destination"`
+	Application    string      `json:"application,omitempty"`
+	TargetRevision string      `json:"targetRevision,omitempty"`
+	ChartVersion   string      `json:"chartVersion,omitempty"`
+	Commit         *git.Commit `json:"commit,omitempty"`
+	// Error explains why the status of the destination is incomplete, e.g. the application file is missing.
+	Error string `json:"error,omitempty"`
+}
+
+// destinationStatuses reads what was last promoted to each destination from its application file.
+// The commit of the targetRevision is only known when it has been fetched into the repository.
+func destinationStatuses(fs billy.Filesystem, repo *git.Repository, files []applicationFile) []destinationStatus {
+	statuses := make([]destinationStatus, 0, len(files))
+
+	for _, file := range files {
+		status := destinationStatus{
+			Group:       file.group,
+			Destination: v1alpha1.CoalesceSanitizeDestination(file.destination),
+		}
+
+		contents, err := billyutil.ReadFile(fs, file.path)
+		if err != nil {
+			status.Error = fmt.Sprintf("reading application file: %q: %s", file.path, err)
+			statuses = append(statuses, status)
+			continue
+		}
+
+		app, err := argocd.ReadApplication(contents)
+		if err != nil {
+			status.Error = fmt.Sprintf("reading application file: %q: %s", file.path, err)
+			statuses = append(statuses, status)
+			continue
+		}
+
+		revisions := argocd.PromotedRevisionsOf(app, file.destination.ArgoCD)
+		status.Application = app.Name
+		status.TargetRevision = revisions.Revision
+		status.ChartVersion = revisions.ChartVersion
+
+		if revisions.Revision != "" {
+			if commit, err := repo.Commit(revisions.Revision); err == nil {
+				status.Commit = &commit
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-billy/v6"
+	"github.com/go-git/go-billy/v6/memfs"
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/lithammer/dedent"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/argocd"
+	"github.com/wmcnamee-coreweave/alveus/internal/integrations/git"
+)
+
+var _ = Describe("destinationStatuses", func() {
+	const basepath = ".alveus/applications"
+
+	var (
+		fs      billy.Filesystem
+		repo    *git.Repository
+		service v1alpha1.Service
+		files   []applicationFile
+
+		deployed string
+		statuses []destinationStatus
+	)
+
+	BeforeEach(func() {
+		repoPath := GinkgoT().TempDir()
+		gitRepo, err := gogit.PlainInit(repoPath, false)
+		Expect(err).NotTo(HaveOccurred())
+		worktree, err := gitRepo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("podinfo"), 0o644)).To(Succeed())
+		_, err = worktree.Add("README.md")
+		Expect(err).NotTo(HaveOccurred())
+		hash, err := worktree.Commit("feat: add podinfo\n\nwith a body", &gogit.CommitOptions{
+			Author: &object.Signature{
+				Name:  "dev",
+				Email: "dev@example.com",
+				When:  time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC),
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		deployed = hash.String()
+
+		repo, err = git.Open(repoPath)
+		Expect(err).NotTo(HaveOccurred())
+
+		service, err = v1alpha1.NewFromYaml([]byte(dedent.Dedent(`
+			name: podinfo
+			destinationNamespace: podinfo
+			argoCD:
+			  source:
+			    path: manifests
+			destinationGroups:
+			- name: staging
+			  destinations:
+			  - name: in-cluster
+			- name: prod
+			  destinations:
+			  - name: in-cluster
+		`)))
+		Expect(err).NotTo(HaveOccurred())
+
+		appRepo, err := generateApps("https://github.com/example/podinfo.git", "not-fetched", basepath, service)
+		Expect(err).NotTo(HaveOccurred())
+
+		fs = memfs.New()
+		Expect(writeAppFixtures(fs, basepath, appRepo.Applications())).To(Succeed())
+
+		files, err = resolveApplicationFiles(service, appRepo)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = rollbackApps(fs, rollbackInput{
+			files:     files,
+			group:     "staging",
+			revisions: argocd.PromotedRevisions{Revision: deployed},
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		statuses = destinationStatuses(fs, repo, files)
+	})
+
+	It("should report the deployed revision and its commit", func() {
+		Expect(statuses).To(HaveLen(2))
+		Expect(statuses[0].Group).To(Equal("staging"))
+		Expect(statuses[0].Destination).To(Equal("in-cluster"))
+		Expect(statuses[0].Application).To(Equal("podinfo-staging-in-cluster"))
+		Expect(statuses[0].TargetRevision).To(Equal(deployed))
+		Expect(statuses[0].Commit).NotTo(BeNil())
+		Expect(statuses[0].Commit.Hash).To(Equal(deployed))
+		Expect(statuses[0].Commit.Subject).To(Equal("feat: add podinfo"))
+		Expect(statuses[0].Commit.Author).To(Equal("dev"))
+		Expect(statuses[0].Commit.Date).To(BeTemporally("==", time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)))
+	})
+
+	It("should report the revision without its commit when it is unknown to the repository", func() {
+		Expect(statuses[1].TargetRevision).To(Equal("not-fetched"))
+		Expect(statuses[1].Commit).To(BeNil())
+		Expect(statuses[1].Error).To(BeEmpty())
+	})
+
+	When("an application file is missing", func() {
+		BeforeEach(func() {
+			Expect(fs.Remove(files[1].path)).To(Succeed())
+		})
+
+		It("should report the error for that destination only", func() {
+			Expect(statuses[0].Error).To(BeEmpty())
+			Expect(statuses[1].Group).To(Equal("prod"))
+			Expect(statuses[1].Error).To(ContainSubstring("reading application file"))
+		})
+	})
+})
@@ -3,3 +3,22 @@ package constants
 const (
 	ArtifactNameApplications = "applications"
 )
+
+const (
+	// WorkflowInputRevision is the workflow input carrying the commit being promoted through every stage of a rollout
+	WorkflowInputRevision = "revision"
+	// ResolveRevisionJobName is the job of the top-level workflow which resolves the commit to promote, once
+	ResolveRevisionJobName = "resolve-revision"
+	// RollbackWorkflowName is the suffix of the workflow redeploying a previous revision to selected destinations
+	RollbackWorkflowName = "rollback"
+	// DeployWindowJobName is the job of each destination workflow checking the deploy windows before promoting
+	DeployWindowJobName = "deploy-window"
+	// WorkflowInputBypassDeployWindow is the workflow input promoting a destination outside of its deploy windows,
+	// which the rollback workflow sets
+	WorkflowInputBypassDeployWindow = "bypass-deploy-window"
+	// RolloutWorkflowName is the workflow deploying the services which depend on each other, in order
+	RolloutWorkflowName = "rollout"
+	// WorkflowInputGroup & WorkflowInputDestination select the destinations of the rollback workflow
+	WorkflowInputGroup       = "group"
+	WorkflowInputDestination = "destination"
+)
@@ -3,10 +3,13 @@ package argocd
 import (
 	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strings"
 
 	argoapisapplication "github.com/argoproj/argo-cd/v3/pkg/apis/application"
 	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"github.com/goccy/go-yaml"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
@@ -21,6 +24,7 @@ type Options struct {
 	SyncPolicy           *argov1alpha1.SyncPolicy
 	Project              string
 	Source               v1alpha1.Source
+	Sources              []v1alpha1.Source
 }
 
 type Option func(*Options)
@@ -68,6 +72,13 @@ func WithSource(src v1alpha1.Source) Option {
 	}
 }
 
+// WithSources produces a multi-source application, taking precedence over WithSource.
+func WithSources(srcs []v1alpha1.Source) Option {
+	return func(o *Options) {
+		o.Sources = srcs
+	}
+}
+
 func FromServiceAPI(service v1alpha1.Service) Option {
 	return func(o *Options) {
 		o.SyncPolicy = &service.ArgoCD.SyncPolicy
@@ -100,20 +111,22 @@ func NewApplication(input Input, options ...Option) (argov1alpha1.Application, e
 		return argov1alpha1.Application{}, err
 	}
 
-	source := argov1alpha1.ApplicationSource{
-		RepoURL:        input.RepoURL,
-		Path:           opts.Source.Path,
-		TargetRevision: input.TargetRevision,
-		Directory: &argov1alpha1.ApplicationSourceDirectory{
-			Recurse: true,
-			Jsonnet: opts.Source.Jsonnet,
-			Exclude: opts.Source.Exclude,
-			Include: opts.Source.Include,
-		},
-	}
+	var source *argov1alpha1.ApplicationSource
+	var sources argov1alpha1.ApplicationSources
+	if len(opts.Sources) > 0 {
+		for idx, src := range opts.Sources {
+			appSource, err := newApplicationSource(input, src)
+			if err != nil {
+				return argov1alpha1.Application{}, fmt.Errorf("sources[%d]: %w", idx, err)
+			}
 
-	if source.Directory.Include == "" {
-		source.Directory.Include = "{*.yml,*.yaml}"
+			sources = append(sources, *appSource)
+		}
+	} else {
+		source, err = newApplicationSource(input, opts.Source)
+		if err != nil {
+			return argov1alpha1.Application{}, err
+		}
 	}
 
 	finalizedName, err := util.SanitizeNameForKubernetes(input.Name)
@@ -141,13 +154,96 @@ func NewApplication(input Input, options ...Option) (argov1alpha1.Application, e
 			Project:           opts.Project,
 			SyncPolicy:        opts.SyncPolicy,
 			IgnoreDifferences: opts.IgnoreDifferences,
-			Source:            &source,
+			Source:            source,
+			Sources:           sources,
 		},
 	}
 
 	return app, nil
 }
 
+func newApplicationSource(input Input, src v1alpha1.Source) (*argov1alpha1.ApplicationSource, error) {
+	source := &argov1alpha1.ApplicationSource{
+		RepoURL:        input.RepoURL,
+		Path:           src.Path,
+		TargetRevision: input.TargetRevision,
+		Ref:            src.Ref,
+	}
+
+	if !src.TracksServiceRepository() {
+		source.RepoURL = util.CoalesceStrings(src.RepoURL, input.RepoURL)
+		source.TargetRevision = util.CoalesceStrings(src.TargetRevision, "HEAD")
+	}
+
+	if src.Helm != nil {
+		helm, err := newApplicationSourceHelm(*src.Helm)
+		if err != nil {
+			return nil, err
+		}
+
+		source.Helm = helm
+
+		if src.IsHelmChartRepository() {
+			source.RepoURL = src.Helm.RepoURL
+			source.Path = ""
+			source.Chart = src.Helm.Chart
+			source.TargetRevision = src.Helm.Version
+		}
+
+		return source, nil
+	}
+
+	if src.Kustomize != nil {
+		source.Kustomize = &argov1alpha1.ApplicationSourceKustomize{
+			NamePrefix:   src.Kustomize.NamePrefix,
+			NameSuffix:   src.Kustomize.NameSuffix,
+			Images:       src.Kustomize.Images,
+			CommonLabels: src.Kustomize.CommonLabels,
+			Patches:      src.Kustomize.Patches,
+			Components:   src.Kustomize.Components,
+		}
+
+		return source, nil
+	}
+
+	// a source that only provides files to other sources (e.g. helm value files) is left unrendered
+	if src.Path == "" && src.Ref != "" {
+		return source, nil
+	}
+
+	source.Directory = &argov1alpha1.ApplicationSourceDirectory{
+		Recurse: true,
+		Jsonnet: src.Jsonnet,
+		Exclude: src.Exclude,
+		Include: src.Include,
+	}
+
+	if source.Directory.Include == "" {
+		source.Directory.Include = "{*.yml,*.yaml}"
+	}
+
+	return source, nil
+}
+
+func newApplicationSourceHelm(helm v1alpha1.HelmSource) (*argov1alpha1.ApplicationSourceHelm, error) {
+	result := &argov1alpha1.ApplicationSourceHelm{
+		ReleaseName: helm.ReleaseName,
+		ValueFiles:  helm.ValueFiles,
+		Parameters:  helm.Parameters,
+	}
+
+	if len(helm.Values) > 0 {
+		values, err := yaml.Marshal(helm.Values)
+		if err != nil {
+			return nil, fmt.Errorf("marshalling helm values: %w", err)
+		}
+
+		result.Values = string(values)
+	}
+
+	return result, nil
+}
+
 func FilenameFor(application argov1alpha1.Application) string {
 	return strings.ToLower(
 		util.Join("-",
@@ -156,22 +252,86 @@ func FilenameFor(application argov1alpha1.Application) string {
 	) + ".yaml"
 }
 
-type ApplicationRepository map[string]argov1alpha1.Application
+// ApplicationKey identifies the Application generated for a single destination
+// of a destination group of a service.
+type ApplicationKey struct {
+	Service     string
+	Group       string
+	Destination string
+}
 
-func (r ApplicationRepository) GetByDestination(dest v1alpha1.Destination) (string, argov1alpha1.Application, bool) {
-	for filePath, app := range r {
-		if app.Spec.Destination.Namespace != dest.Namespace {
-			continue
-		}
+func NewApplicationKey(service, group string, destination v1alpha1.Destination) ApplicationKey {
+	return ApplicationKey{
+		Service:     service,
+		Group:       group,
+		Destination: v1alpha1.DestinationIdentity(destination),
+	}
+}
+
+func (k ApplicationKey) String() string {
+	return util.Join("/", k.Service, k.Group, k.Destination)
+}
+
+type ApplicationEntry struct {
+	FilePath    string
+	Application argov1alpha1.Application
+}
+
+type ApplicationRepository map[ApplicationKey]ApplicationEntry
 
-		if app.Spec.Destination.Server == dest.Server {
-			return filePath, app, true
+// Add registers an application, refusing anything that would make a later lookup ambiguous,
+// i.e. the same key, the same file, or the same application name being claimed twice.
+func (r ApplicationRepository) Add(key ApplicationKey, filePath string, app argov1alpha1.Application) error {
+	if existing, ok := r[key]; ok {
+		return fmt.Errorf("duplicate application for %s: %q and %q", key, existing.FilePath, filePath)
+	}
+
+	for existingKey, existing := range r {
+		if existing.FilePath == filePath {
+			return fmt.Errorf("application file %q claimed by both %s and %s", filePath, existingKey, key)
 		}
 
-		if app.Spec.Destination.Name == dest.Name {
-			return filePath, app, true
+		if existing.Application.Namespace == app.Namespace && existing.Application.Name == app.Name {
+			return fmt.Errorf("application name %q claimed by both %s and %s", app.Name, existingKey, key)
 		}
 	}
 
-	return "", argov1alpha1.Application{}, false
+	r[key] = ApplicationEntry{
+		FilePath:    filePath,
+		Application: app,
+	}
+
+	return nil
+}
+
+func (r ApplicationRepository) Get(key ApplicationKey) (ApplicationEntry, error) {
+	entry, ok := r[key]
+	if !ok {
+		return ApplicationEntry{}, fmt.Errorf("no application found for %s", key)
+	}
+
+	return entry, nil
+}
+
+func (r ApplicationRepository) GetByDestination(service, group string, dest v1alpha1.Destination) (string, argov1alpha1.Application, error) {
+	entry, err := r.Get(NewApplicationKey(service, group, dest))
+	if err != nil {
+		return "", argov1alpha1.Application{}, err
+	}
+
+	return entry.FilePath, entry.Application, nil
+}
+
+// Applications returns every application in the repository, ordered by file path.
+func (r ApplicationRepository) Applications() []argov1alpha1.Application {
+	entries := slices.SortedFunc(maps.Values(r), func(a, b ApplicationEntry) int {
+		return strings.Compare(a.FilePath, b.FilePath)
+	})
+
+	apps := make([]argov1alpha1.Application, 0, len(entries))
+	for _, entry := range entries {
+		apps = append(apps, entry.Application)
+	}
+
+	return apps
 }
@@ -0,0 +1,205 @@
+package argocd
+
+import (
+	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+)
+
+var _ = Describe("NewApplication", func() {
+	var (
+		input  Input
+		source v1alpha1.Source
+
+		app       argov1alpha1.Application
+		actualErr error
+	)
+
+	BeforeEach(func() {
+		input = Input{
+			Name:           "podinfo-staging-in-cluster",
+			RepoURL:        "https://github.com/example/podinfo.git",
+			TargetRevision: "HEAD",
+			Destination: v1alpha1.Destination{
+				Name:      "in-cluster",
+				Namespace: "podinfo",
+			},
+		}
+		source = v1alpha1.Source{
+			Path: "manifests",
+		}
+	})
+
+	JustBeforeEach(func() {
+		app, actualErr = NewApplication(input, WithSource(source))
+	})
+
+	It("should render a directory source", func() {
+		Expect(actualErr).NotTo(HaveOccurred())
+		Expect(app.Spec.Source.RepoURL).To(Equal("https://github.com/example/podinfo.git"))
+		Expect(app.Spec.Source.Path).To(Equal("manifests"))
+		Expect(app.Spec.Source.TargetRevision).To(Equal("HEAD"))
+		Expect(app.Spec.Source.Directory).NotTo(BeNil())
+		Expect(app.Spec.Source.Helm).To(BeNil())
+	})
+
+	When("the source is a helm chart in the repository", func() {
+		BeforeEach(func() {
+			source.Path = "charts/podinfo"
+			source.Helm = &v1alpha1.HelmSource{
+				ReleaseName: "podinfo",
+				ValueFiles:  []string{"values-staging.yaml"},
+				Values: map[string]any{
+					"replicas": 2,
+				},
+			}
+		})
+
+		It("should render a helm source tracking the repository", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(app.Spec.Source.RepoURL).To(Equal("https://github.com/example/podinfo.git"))
+			Expect(app.Spec.Source.Path).To(Equal("charts/podinfo"))
+			Expect(app.Spec.Source.TargetRevision).To(Equal("HEAD"))
+			Expect(app.Spec.Source.Directory).To(BeNil())
+			Expect(app.Spec.Source.Helm.ReleaseName).To(Equal("podinfo"))
+			Expect(app.Spec.Source.Helm.ValueFiles).To(Equal([]string{"values-staging.yaml"}))
+			Expect(app.Spec.Source.Helm.Values).To(Equal("replicas: 2\n"))
+		})
+	})
+
+	When("the source is a kustomization", func() {
+		BeforeEach(func() {
+			source.Path = "overlays/staging"
+			source.Kustomize = &v1alpha1.KustomizeSource{
+				NameSuffix: "-staging",
+				Images:     argov1alpha1.KustomizeImages{"podinfo:6.9.2"},
+			}
+		})
+
+		It("should render a kustomize source", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(app.Spec.Source.Path).To(Equal("overlays/staging"))
+			Expect(app.Spec.Source.Directory).To(BeNil())
+			Expect(app.Spec.Source.Kustomize.NameSuffix).To(Equal("-staging"))
+			Expect(app.Spec.Source.Kustomize.Images).To(Equal(argov1alpha1.KustomizeImages{"podinfo:6.9.2"}))
+		})
+	})
+
+	When("the source is a chart from a helm repository", func() {
+		BeforeEach(func() {
+			source.Helm = &v1alpha1.HelmSource{
+				RepoURL: "https://charts.example.com",
+				Chart:   "podinfo",
+				Version: "6.9.2",
+			}
+		})
+
+		It("should render a helm source tracking the chart version", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(app.Spec.Source.RepoURL).To(Equal("https://charts.example.com"))
+			Expect(app.Spec.Source.Path).To(BeEmpty())
+			Expect(app.Spec.Source.Chart).To(Equal("podinfo"))
+			Expect(app.Spec.Source.TargetRevision).To(Equal("6.9.2"))
+		})
+	})
+})
+
+var _ = Describe("ApplicationRepository", func() {
+	var (
+		repo        ApplicationRepository
+		destination v1alpha1.Destination
+	)
+
+	newApp := func(name string) argov1alpha1.Application {
+		return argov1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "argocd",
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		repo = make(ApplicationRepository)
+		destination = v1alpha1.Destination{
+			Name:      "in-cluster",
+			Namespace: "podinfo",
+		}
+	})
+
+	When("the same destination is used by multiple groups", func() {
+		BeforeEach(func() {
+			Expect(repo.Add(
+				NewApplicationKey("podinfo", "staging", destination),
+				"apps/podinfo-staging-in-cluster.yaml",
+				newApp("podinfo-staging-in-cluster"),
+			)).To(Succeed())
+			Expect(repo.Add(
+				NewApplicationKey("podinfo", "prod", destination),
+				"apps/podinfo-prod-in-cluster.yaml",
+				newApp("podinfo-prod-in-cluster"),
+			)).To(Succeed())
+		})
+
+		It("should return the application of the requested group", func() {
+			for _, group := range []string{"staging", "prod"} {
+				filePath, app, err := repo.GetByDestination("podinfo", group, destination)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(filePath).To(Equal("apps/podinfo-" + group + "-in-cluster.yaml"))
+				Expect(app.Name).To(Equal("podinfo-" + group + "-in-cluster"))
+			}
+		})
+
+		It("should err for an unknown group", func() {
+			_, _, err := repo.GetByDestination("podinfo", "dev", destination)
+			Expect(err).To(MatchError("no application found for podinfo/dev/in-cluster/namespace/podinfo"))
+		})
+
+		It("should list applications ordered by file path", func() {
+			apps := repo.Applications()
+			Expect(apps).To(HaveLen(2))
+			Expect(apps[0].Name).To(Equal("podinfo-prod-in-cluster"))
+			Expect(apps[1].Name).To(Equal("podinfo-staging-in-cluster"))
+		})
+	})
+
+	Context("adding an application", func() {
+		BeforeEach(func() {
+			Expect(repo.Add(
+				NewApplicationKey("podinfo", "staging", destination),
+				"apps/podinfo-staging-in-cluster.yaml",
+				newApp("podinfo-staging-in-cluster"),
+			)).To(Succeed())
+		})
+
+		It("should err when the key is already taken", func() {
+			err := repo.Add(
+				NewApplicationKey("podinfo", "staging", destination),
+				"apps/other.yaml",
+				newApp("other"),
+			)
+			Expect(err).To(MatchError(`duplicate application for podinfo/staging/in-cluster/namespace/podinfo: "apps/podinfo-staging-in-cluster.yaml" and "apps/other.yaml"`))
+		})
+
+		It("should err when the file path is already taken", func() {
+			err := repo.Add(
+				NewApplicationKey("podinfo", "prod", destination),
+				"apps/podinfo-staging-in-cluster.yaml",
+				newApp("podinfo-prod-in-cluster"),
+			)
+			Expect(err).To(MatchError(`application file "apps/podinfo-staging-in-cluster.yaml" claimed by both podinfo/staging/in-cluster/namespace/podinfo and podinfo/prod/in-cluster/namespace/podinfo`))
+		})
+
+		It("should err when the application name is already taken", func() {
+			err := repo.Add(
+				NewApplicationKey("podinfo", "prod", destination),
+				"apps/podinfo-prod-in-cluster.yaml",
+				newApp("podinfo-staging-in-cluster"),
+			)
+			Expect(err).To(MatchError(`application name "podinfo-staging-in-cluster" claimed by both podinfo/staging/in-cluster/namespace/podinfo and podinfo/prod/in-cluster/namespace/podinfo`))
+		})
+	})
+})
@@ -0,0 +1,98 @@
+package argocd
+
+import (
+	"errors"
+	"fmt"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+// ReadApplication parses an application file, such as one written by generate.
+func ReadApplication(contents []byte) (argov1alpha1.Application, error) {
+	var app argov1alpha1.Application
+	if err := yaml.Unmarshal(contents, &app); err != nil {
+		return argov1alpha1.Application{}, fmt.Errorf("unmarshalling application: %w", err)
+	}
+
+	return app, nil
+}
+
+// PromotedRevisions are the values the deploy job writes to the targetRevision of the promoted sources.
+type PromotedRevisions struct {
+	// Revision is the commit of the service repository, written to the sources tracking it.
+	Revision string
+	// ChartVersion is written to the sources pulling a chart from a helm repository.
+	ChartVersion string
+}
+
+// SetPromotedRevisions sets the targetRevision of the promoted sources of the application,
+// i.e. the same fields the deploy job writes when promoting.
+func SetPromotedRevisions(app *argov1alpha1.Application, argoCDSpec v1alpha1.ArgoCD, revisions PromotedRevisions) error {
+	set := func(appSource *argov1alpha1.ApplicationSource, src v1alpha1.Source) error {
+		if appSource == nil {
+			return errors.New("application has no source")
+		}
+
+		if !src.IsPromoted() {
+			return nil
+		}
+
+		revision := revisions.Revision
+		if src.IsHelmChartRepository() {
+			revision = revisions.ChartVersion
+		}
+
+		if revision == "" {
+			if src.IsHelmChartRepository() {
+				return fmt.Errorf("a chart version is required to promote chart %s", src.Helm.Chart)
+			}
+
+			return errors.New("a revision is required to promote the service repository")
+		}
+
+		appSource.TargetRevision = revision
+
+		return nil
+	}
+
+	if !argoCDSpec.IsMultiSource() {
+		return set(app.Spec.Source, argoCDSpec.Source)
+	}
+
+	if len(app.Spec.Sources) != len(argoCDSpec.Sources) {
+		return fmt.Errorf("application has %d sources, but %d are specified", len(app.Spec.Sources), len(argoCDSpec.Sources))
+	}
+
+	for idx, src := range argoCDSpec.Sources {
+		if err := set(&app.Spec.Sources[idx], src); err != nil {
+			return fmt.Errorf("sources[%d]: %w", idx, err)
+		}
+	}
+
+	return nil
+}
+
+// PromotedRevisionsOf reads the targetRevision of the promoted sources of the application,
+// i.e. what was last promoted to it.
+func PromotedRevisionsOf(app argov1alpha1.Application, argoCDSpec v1alpha1.ArgoCD) PromotedRevisions {
+	var revisions PromotedRevisions
+
+	appSources := app.Spec.GetSources()
+	for idx, src := range argoCDSpec.EffectiveSources() {
+		if idx >= len(appSources) || !src.IsPromoted() {
+			continue
+		}
+
+		if src.IsHelmChartRepository() {
+			revisions.ChartVersion = util.CoalesceStrings(revisions.ChartVersion, appSources[idx].TargetRevision)
+		} else {
+			revisions.Revision = util.CoalesceStrings(revisions.Revision, appSources[idx].TargetRevision)
+		}
+	}
+
+	return revisions
+}
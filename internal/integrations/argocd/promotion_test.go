@@ -0,0 +1,77 @@
+package argocd
+
+import (
+	argov1alpha1 "github.com/argoproj/argo-cd/v3/pkg/apis/application/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+)
+
+var _ = Describe("SetPromotedRevisions", func() {
+	var (
+		argoCDSpec v1alpha1.ArgoCD
+		revisions  PromotedRevisions
+
+		app       argov1alpha1.Application
+		actualErr error
+	)
+
+	BeforeEach(func() {
+		argoCDSpec = v1alpha1.ArgoCD{
+			Sources: []v1alpha1.Source{
+				{
+					Helm: &v1alpha1.HelmSource{
+						RepoURL: "https://stefanprodan.github.io/podinfo",
+						Chart:   "podinfo",
+						Version: "6.5.0",
+					},
+				},
+				{
+					RepoURL: "https://github.com/example/values.git",
+					Ref:     "values",
+				},
+				{
+					Path: "manifests",
+				},
+			},
+		}
+		revisions = PromotedRevisions{
+			Revision:     "abc123",
+			ChartVersion: "6.4.0",
+		}
+
+		var err error
+		app, err = NewApplication(Input{
+			Name:           "podinfo-staging-in-cluster",
+			RepoURL:        "https://github.com/example/podinfo.git",
+			TargetRevision: "HEAD",
+			Destination: v1alpha1.Destination{
+				Name:      "in-cluster",
+				Namespace: "podinfo",
+			},
+		}, WithSources(argoCDSpec.Sources))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	JustBeforeEach(func() {
+		actualErr = SetPromotedRevisions(&app, argoCDSpec, revisions)
+	})
+
+	It("should only set the targetRevision of the promoted sources", func() {
+		Expect(actualErr).NotTo(HaveOccurred())
+		Expect(app.Spec.Sources[0].TargetRevision).To(Equal("6.4.0"))
+		Expect(app.Spec.Sources[1].TargetRevision).To(Equal("HEAD"))
+		Expect(app.Spec.Sources[2].TargetRevision).To(Equal("abc123"))
+	})
+
+	When("no chart version is given for a chart from a helm repository", func() {
+		BeforeEach(func() {
+			revisions.ChartVersion = ""
+		})
+
+		It("should err", func() {
+			Expect(actualErr).To(MatchError("sources[0]: a chart version is required to promote chart podinfo"))
+		})
+	})
+})
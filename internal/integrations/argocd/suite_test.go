@@ -0,0 +1,13 @@
+package argocd
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestArgoCD(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "argocd Suite")
+}
@@ -0,0 +1,137 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+type Repository struct {
+	repo *gogit.Repository
+}
+
+// Open opens the repository containing path.
+func Open(path string) (*Repository, error) {
+	repo, err := gogit.PlainOpenWithOptions(path, &gogit.PlainOpenOptions{
+		DetectDotGit: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("opening git repository: %q: %w", path, err)
+	}
+
+	return &Repository{repo: repo}, nil
+}
+
+var commitHashPattern = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// IsCommitHash is true for (abbreviated) commit hashes, as opposed to symbolic revisions like branches or HEAD,
+// which refer to different commits over time.
+func IsCommitHash(revision string) bool {
+	return commitHashPattern.MatchString(revision)
+}
+
+type Commit struct {
+	Hash    string    `json:"hash"`
+	Subject string    `json:"subject"`
+	Author  string    `json:"author"`
+	Date    time.Time `json:"date"`
+}
+
+// Commit resolves a revision (commit, branch, tag, HEAD, etc.) to its commit.
+func (r *Repository) Commit(revision string) (Commit, error) {
+	hash, err := r.repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return Commit{}, fmt.Errorf("resolving revision: %q: %w", revision, err)
+	}
+
+	commit, err := r.repo.CommitObject(*hash)
+	if err != nil {
+		return Commit{}, fmt.Errorf("reading commit: %s: %w", hash, err)
+	}
+
+	return newCommit(commit), nil
+}
+
+func newCommit(commit *object.Commit) Commit {
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+
+	return Commit{
+		Hash:    commit.Hash.String(),
+		Subject: subject,
+		Author:  commit.Author.Name,
+		Date:    commit.Committer.When,
+	}
+}
+
+// FileRevision is the content of a file as of a commit which changed it.
+type FileRevision struct {
+	Commit   Commit
+	Contents []byte
+}
+
+// FileHistory lists the commits reachable from HEAD which changed the file at path, oldest first.
+// The path is relative to the working directory, as is the path passed to Open.
+func (r *Repository) FileHistory(path string) ([]FileRevision, error) {
+	worktree, err := r.repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("opening worktree: %w", err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("resolving path: %q: %w", path, err)
+	}
+
+	relPath, err := filepath.Rel(worktree.Filesystem.Root(), absPath)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		return nil, fmt.Errorf("path is outside of the repository: %q", path)
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	commits, err := r.repo.Log(&gogit.LogOptions{
+		FileName: &relPath,
+		Order:    gogit.LogOrderCommitterTime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading history: %q: %w", relPath, err)
+	}
+
+	var history []FileRevision
+	err = commits.ForEach(func(commit *object.Commit) error {
+		file, err := commit.File(relPath)
+		if errors.Is(err, object.ErrFileNotFound) {
+			// deleted by the commit
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading file: %q: %s: %w", relPath, commit.Hash, err)
+		}
+
+		contents, err := file.Contents()
+		if err != nil {
+			return fmt.Errorf("reading file: %q: %s: %w", relPath, commit.Hash, err)
+		}
+
+		history = append(history, FileRevision{
+			Commit:   newCommit(commit),
+			Contents: []byte(contents),
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(history)
+
+	return history, nil
+}
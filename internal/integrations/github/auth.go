@@ -0,0 +1,181 @@
+package github
+
+import (
+	"github.com/cakehappens/gocto"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+const (
+	EnvNameArgoCDServer    = "ARGOCD_SERVER"
+	EnvNameArgoCDOpts      = "ARGOCD_OPTS"
+	EnvNameArgoCDAuthToken = "ARGOCD_AUTH_TOKEN"
+	EnvNameArgoCDUsername  = "ARGOCD_USERNAME"
+	EnvNameArgoCDPassword  = "ARGOCD_PASSWORD"
+)
+
+// newArgoCDEnv configures the argocd CLI of the deploy job through the environment variables it reads.
+func newArgoCDEnv(server *v1alpha1.ArgoCDServer) map[string]string {
+	if server == nil {
+		return nil
+	}
+
+	var opts []string
+	if server.GRPCWeb {
+		opts = append(opts, "--grpc-web")
+	}
+
+	if server.Insecure {
+		opts = append(opts, "--insecure")
+	}
+
+	if server.PlainText {
+		opts = append(opts, "--plaintext")
+	}
+
+	env := map[string]string{
+		EnvNameArgoCDServer: server.Address,
+	}
+
+	if len(opts) > 0 {
+		env[EnvNameArgoCDOpts] = util.Join(" ", opts...)
+	}
+
+	if server.Auth.AuthTokenSecretName != "" {
+		env[EnvNameArgoCDAuthToken] = secretExpression(server.Auth.AuthTokenSecretName)
+	}
+
+	return env
+}
+
+// newArgoCDLoginSteps authenticates the argocd CLI, unless no login is needed,
+// e.g. when authenticating with an API token, which the CLI reads from its environment.
+func newArgoCDLoginSteps(server *v1alpha1.ArgoCDServer) []gocto.Step {
+	if server == nil {
+		return nil
+	}
+
+	if server.Auth.UsernameSecretName != "" {
+		return []gocto.Step{
+			{
+				Name: "argocd-login",
+				Env: map[string]string{
+					EnvNameArgoCDUsername: secretExpression(server.Auth.UsernameSecretName),
+					EnvNameArgoCDPassword: secretExpression(server.Auth.PasswordSecretName),
+				},
+				Run: util.SprintfDedent(`
+						argocd login \
+							"${%s}" \
+							--username "${%s}" \
+							--password "${%s}" \
+							;
+					`, EnvNameArgoCDServer, EnvNameArgoCDUsername, EnvNameArgoCDPassword),
+			},
+		}
+	}
+
+	if server.Auth.OIDC != nil {
+		const (
+			EnvNameAudience    = "OIDC_AUDIENCE"
+			EnvNameConnectorID = "DEX_CONNECTOR_ID"
+			EnvNameClientID    = "DEX_CLIENT_ID"
+		)
+
+		scheme := "https"
+		if server.PlainText {
+			scheme = "http"
+		}
+
+		curlOpts := "-sSf"
+		if server.Insecure {
+			curlOpts += "k"
+		}
+
+		return []gocto.Step{
+			{
+				Name: "argocd-oidc-login",
+				Env: map[string]string{
+					EnvNameAudience:    util.CoalesceStrings(server.Auth.OIDC.Audience, server.Address),
+					EnvNameConnectorID: server.Auth.OIDC.ConnectorID,
+					EnvNameClientID:    util.CoalesceStrings(server.Auth.OIDC.ClientID, "argo-cd-cli"),
+				},
+				Run: util.SprintfDedent(`
+						ID_TOKEN=$(curl -sSf \
+							-H "Authorization: bearer ${ACTIONS_ID_TOKEN_REQUEST_TOKEN}" \
+							"${ACTIONS_ID_TOKEN_REQUEST_URL}&audience=${%[1]s}" \
+							| jq -r '.value')
+
+						TOKEN=$(curl %[2]s \
+							"%[3]s://${%[4]s}/api/dex/token" \
+							--data-urlencode "grant_type=urn:ietf:params:oauth:grant-type:token-exchange" \
+							--data-urlencode "subject_token=${ID_TOKEN}" \
+							--data-urlencode "subject_token_type=urn:ietf:params:oauth:token-type:id_token" \
+							--data-urlencode "requested_token_type=urn:ietf:params:oauth:token-type:id_token" \
+							--data-urlencode "connector_id=${%[5]s}" \
+							--data-urlencode "client_id=${%[6]s}" \
+							--data-urlencode "scope=openid groups" \
+							| jq -r '.access_token')
+
+						echo "::add-mask::${TOKEN}"
+						echo "%[7]s=${TOKEN}" >> "${GITHUB_ENV}"
+					`, EnvNameAudience, curlOpts, scheme, EnvNameArgoCDServer,
+					EnvNameConnectorID, EnvNameClientID, EnvNameArgoCDAuthToken),
+			},
+		}
+	}
+
+	return nil
+}
+
+// deployPermissions are the token permissions of the deploy job of a destination.
+// Permissions are left to the repository defaults, unless the job needs an OIDC token or opens pull requests,
+// in which case everything else the job needs must be requested too.
+func deployPermissions(dest v1alpha1.Destination) gocto.Permissions {
+	var permissions gocto.Permissions
+
+	if dest.ArgoCD.Server != nil && dest.ArgoCD.Server.Auth.OIDC != nil {
+		permissions.IDToken = gocto.AccessLevelWrite
+	}
+
+	if dest.Github.Promotion != nil && dest.Github.Promotion.Strategy == v1alpha1.PromotionStrategyPullRequest {
+		permissions.PullRequests = gocto.AccessLevelWrite
+	}
+
+	if permissions == (gocto.Permissions{}) {
+		return permissions
+	}
+
+	// the promotion commit is always pushed, whether to the commit branch or to the branch of the pull request
+	permissions.Contents = gocto.AccessLevelWrite
+
+	return permissions
+}
+
+// mergePermissions combines the permissions deploy jobs need,
+// as a job calling a reusable workflow must grant at least what the called jobs request.
+func mergePermissions(a, b gocto.Permissions) gocto.Permissions {
+	higher := func(x, y gocto.AccessLevel) gocto.AccessLevel {
+		rank := map[gocto.AccessLevel]int{
+			gocto.AccessLevelNone:  1,
+			gocto.AccessLevelRead:  2,
+			gocto.AccessLevelWrite: 3,
+		}
+
+		if rank[y] > rank[x] {
+			return y
+		}
+
+		return x
+	}
+
+	a.Contents = higher(a.Contents, b.Contents)
+	a.IDToken = higher(a.IDToken, b.IDToken)
+	a.PullRequests = higher(a.PullRequests, b.PullRequests)
+
+	return a
+}
+
+func secretExpression(name string) string {
+	return "${{ secrets." + name + " }}"
+}
@@ -0,0 +1,79 @@
+package github
+
+import (
+	"github.com/cakehappens/gocto"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+)
+
+var _ = Describe("deployPermissions()", func() {
+	var dest v1alpha1.Destination
+
+	BeforeEach(func() {
+		dest = v1alpha1.Destination{Name: "in-cluster"}
+	})
+
+	It("should leave the permissions to the repository defaults", func() {
+		Expect(deployPermissions(dest)).To(Equal(gocto.Permissions{}))
+	})
+
+	Context("authenticating with OIDC", func() {
+		BeforeEach(func() {
+			dest.ArgoCD.Server = &v1alpha1.ArgoCDServer{
+				Address: "argocd.example.com",
+				Auth: v1alpha1.ArgoCDAuth{
+					OIDC: &v1alpha1.ArgoCDOIDCAuth{ConnectorID: "github"},
+				},
+			}
+		})
+
+		It("should request an ID token", func() {
+			Expect(deployPermissions(dest)).To(Equal(gocto.Permissions{
+				Contents: gocto.AccessLevelWrite,
+				IDToken:  gocto.AccessLevelWrite,
+			}))
+		})
+	})
+
+	Context("promoting with pull requests", func() {
+		BeforeEach(func() {
+			dest.Github.Promotion = &v1alpha1.Promotion{Strategy: v1alpha1.PromotionStrategyPullRequest}
+		})
+
+		It("should request to open pull requests", func() {
+			Expect(deployPermissions(dest)).To(Equal(gocto.Permissions{
+				Contents:     gocto.AccessLevelWrite,
+				PullRequests: gocto.AccessLevelWrite,
+			}))
+		})
+	})
+})
+
+var _ = Describe("mergePermissions()", func() {
+	It("should keep the higher access level of each permission", func() {
+		a := gocto.Permissions{
+			Contents: gocto.AccessLevelRead,
+			IDToken:  gocto.AccessLevelWrite,
+		}
+		b := gocto.Permissions{
+			Contents:     gocto.AccessLevelWrite,
+			IDToken:      gocto.AccessLevelNone,
+			PullRequests: gocto.AccessLevelRead,
+		}
+
+		expected := gocto.Permissions{
+			Contents:     gocto.AccessLevelWrite,
+			IDToken:      gocto.AccessLevelWrite,
+			PullRequests: gocto.AccessLevelRead,
+		}
+
+		Expect(mergePermissions(a, b)).To(Equal(expected))
+		Expect(mergePermissions(b, a)).To(Equal(expected))
+	})
+
+	It("should leave unset permissions unset", func() {
+		Expect(mergePermissions(gocto.Permissions{}, gocto.Permissions{})).To(Equal(gocto.Permissions{}))
+	})
+})
@@ -0,0 +1,128 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cakehappens/gocto"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+// batchGateJobName is the job deciding whether a batch (numbered from 1) of the group is deployed.
+func batchGateJobName(batch int) string {
+	return fmt.Sprintf("batch-%d-gate", batch)
+}
+
+// orderBatches makes the deploy jobs of a group workflow wait for the previous batches of the group.
+// Without failures to tolerate, a batch simply needs the previous one, so that any failure halts the remaining batches.
+// Otherwise, a gate job counts the failed destinations before each batch, and halts the remaining batches
+// once more than maxFailures have failed, or the canary has.
+// The group workflow still fails when any destination fails, so dependent groups are never deployed.
+func orderBatches(jobs map[string]gocto.Job, group v1alpha1.DestinationGroup) error {
+	batches := group.Batches()
+	if len(batches) < 2 {
+		return nil
+	}
+
+	var maxFailures int
+	var canary string
+	if group.RolloutStrategy != nil {
+		maxFailures = group.RolloutStrategy.MaxFailures
+		canary = group.RolloutStrategy.Canary
+	}
+
+	var deployed []string
+	var previousGate string
+	for idx, batch := range batches {
+		names := make([]string, 0, len(batch))
+		for _, dest := range batch {
+			names = append(names, v1alpha1.CoalesceSanitizeDestination(dest))
+		}
+
+		if idx > 0 {
+			needs := deployed[len(deployed)-len(batches[idx-1]):]
+			var condition string
+
+			if maxFailures > 0 {
+				gate := batchGateJobName(idx + 1)
+				if _, ok := jobs[gate]; ok {
+					return fmt.Errorf("destination name is reserved: %s", gate)
+				}
+
+				conditions := []string{"!cancelled()"}
+				if previousGate != "" {
+					conditions = append(conditions, jobSucceeded(previousGate))
+				}
+				if canary != "" && idx == 1 {
+					conditions = append(conditions, jobSucceeded(canary))
+				}
+
+				gateNeeds := append([]string(nil), deployed...)
+				if previousGate != "" {
+					gateNeeds = append(gateNeeds, previousGate)
+				}
+
+				jobs[gate] = newBatchGateJob(gate, gateNeeds, strings.Join(conditions, " && "), maxFailures)
+
+				needs = []string{gate}
+				condition = "!cancelled() && " + jobSucceeded(gate)
+				previousGate = gate
+			}
+
+			for _, name := range names {
+				job := jobs[name]
+				job.Needs = append([]string(nil), needs...)
+				job.If = condition
+				jobs[name] = job
+			}
+		}
+
+		deployed = append(deployed, names...)
+	}
+
+	return nil
+}
+
+func jobSucceeded(name string) string {
+	return fmt.Sprintf("needs.%s.result == 'success'", name)
+}
+
+// newBatchGateJob fails, halting the next batches, once more than maxFailures of the jobs it needs have failed.
+// Only deploy jobs are counted, as the previous gate it needs has succeeded.
+func newBatchGateJob(name string, needs []string, condition string, maxFailures int) gocto.Job {
+	const (
+		EnvNameNeeds       = "NEEDS"
+		EnvNameMaxFailures = "MAX_FAILURES"
+	)
+
+	return gocto.Job{
+		Name:   name,
+		Needs:  needs,
+		If:     condition,
+		RunsOn: []string{"ubuntu-latest"},
+		Defaults: gocto.Defaults{
+			Run: gocto.DefaultsRun{
+				Shell: gocto.ShellBash,
+			},
+		},
+		Steps: []gocto.Step{
+			{
+				Name: "count-failures",
+				Env: map[string]string{
+					EnvNameNeeds:       "${{ toJSON(needs) }}",
+					EnvNameMaxFailures: fmt.Sprintf("%d", maxFailures),
+				},
+				Run: util.SprintfDedent(`
+						FAILURES=$(jq '[.[] | select(.result == "failure")] | length' <<< "${%[1]s}")
+						echo "${FAILURES} destination(s) failed, at most ${%[2]s} may fail"
+						if (( FAILURES > %[2]s )); then
+							echo "::error::halting the remaining batches, ${FAILURES} destination(s) failed"
+							exit 1
+						fi
+					`, EnvNameNeeds, EnvNameMaxFailures),
+			},
+		},
+	}
+}
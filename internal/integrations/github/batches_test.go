@@ -0,0 +1,126 @@
+package github
+
+import (
+	"github.com/cakehappens/gocto"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+)
+
+var _ = Describe("orderBatches()", func() {
+	var (
+		group v1alpha1.DestinationGroup
+		jobs  map[string]gocto.Job
+
+		actualErr error
+	)
+
+	BeforeEach(func() {
+		group = v1alpha1.DestinationGroup{
+			Name: "prod",
+			Destinations: []v1alpha1.Destination{
+				{Name: "canary"},
+				{Name: "a"},
+				{Name: "b"},
+				{Name: "c"},
+				{Name: "d"},
+			},
+			RolloutStrategy: &v1alpha1.RolloutStrategy{
+				Canary:  "canary",
+				Batches: []string{"2"},
+			},
+		}
+
+		jobs = map[string]gocto.Job{}
+		for _, dest := range group.Destinations {
+			jobs[dest.Name] = gocto.Job{Name: dest.Name}
+		}
+	})
+
+	JustBeforeEach(func() {
+		actualErr = orderBatches(jobs, group)
+	})
+
+	Context("without failures to tolerate", func() {
+		It("should make each batch need the previous one", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(jobs).To(HaveLen(5))
+
+			Expect(jobs["canary"].Needs).To(BeEmpty())
+			Expect(jobs["a"].Needs).To(Equal([]string{"canary"}))
+			Expect(jobs["b"].Needs).To(Equal([]string{"canary"}))
+			Expect(jobs["c"].Needs).To(Equal([]string{"a", "b"}))
+			Expect(jobs["d"].Needs).To(Equal([]string{"a", "b"}))
+			Expect(jobs["d"].If).To(BeEmpty())
+		})
+	})
+
+	Context("with failures to tolerate", func() {
+		BeforeEach(func() {
+			group.RolloutStrategy.MaxFailures = 1
+		})
+
+		It("should gate each batch on the failures of the previous batches", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(jobs).To(HaveLen(7))
+
+			Expect(jobs["batch-2-gate"].Needs).To(Equal([]string{"canary"}))
+			Expect(jobs["batch-2-gate"].If).To(Equal("!cancelled() && needs.canary.result == 'success'"))
+			Expect(jobs["batch-2-gate"].Steps[0].Env).To(HaveKeyWithValue("MAX_FAILURES", "1"))
+
+			Expect(jobs["batch-3-gate"].Needs).To(Equal([]string{"canary", "a", "b", "batch-2-gate"}))
+			Expect(jobs["batch-3-gate"].If).To(Equal("!cancelled() && needs.batch-2-gate.result == 'success'"))
+		})
+
+		It("should make each batch need its gate", func() {
+			for _, name := range []string{"a", "b"} {
+				Expect(jobs[name].Needs).To(Equal([]string{"batch-2-gate"}))
+				Expect(jobs[name].If).To(Equal("!cancelled() && needs.batch-2-gate.result == 'success'"))
+			}
+
+			for _, name := range []string{"c", "d"} {
+				Expect(jobs[name].Needs).To(Equal([]string{"batch-3-gate"}))
+				Expect(jobs[name].If).To(Equal("!cancelled() && needs.batch-3-gate.result == 'success'"))
+			}
+		})
+
+		Context("without a canary", func() {
+			BeforeEach(func() {
+				group.RolloutStrategy.Canary = ""
+			})
+
+			It("should not wait for the first batch to succeed", func() {
+				Expect(actualErr).NotTo(HaveOccurred())
+				Expect(jobs["batch-2-gate"].Needs).To(Equal([]string{"canary", "a"}))
+				Expect(jobs["batch-2-gate"].If).To(Equal("!cancelled()"))
+			})
+		})
+
+		Context("a destination named after a gate", func() {
+			BeforeEach(func() {
+				group.Destinations[4].Name = "batch-2-gate"
+				jobs = map[string]gocto.Job{}
+				for _, dest := range group.Destinations {
+					jobs[dest.Name] = gocto.Job{Name: dest.Name}
+				}
+			})
+
+			It("should error", func() {
+				Expect(actualErr).To(MatchError("destination name is reserved: batch-2-gate"))
+			})
+		})
+	})
+
+	Context("a single batch", func() {
+		BeforeEach(func() {
+			group.RolloutStrategy = nil
+		})
+
+		It("should leave the jobs as-is", func() {
+			Expect(actualErr).NotTo(HaveOccurred())
+			Expect(jobs).To(HaveLen(5))
+			Expect(jobs["d"].Needs).To(BeEmpty())
+		})
+	})
+})
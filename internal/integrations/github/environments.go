@@ -0,0 +1,54 @@
+package github
+
+import (
+	"cmp"
+	"reflect"
+	"slices"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+)
+
+// RequiredEnvironment is a GitHub environment deployed to by the generated workflows,
+// which must be created (and protected) in the repository settings.
+type RequiredEnvironment struct {
+	Name       string                         `json:"name"`
+	Protection v1alpha1.EnvironmentProtection `json:"protection,omitempty,omitzero"`
+	// Destinations deploying to the environment, as <group>/<destination>.
+	Destinations []string `json:"destinations"`
+}
+
+// NewEnvironmentsReport lists the environments the workflows of the service deploy to, sorted by name.
+func NewEnvironmentsReport(service v1alpha1.Service) []RequiredEnvironment {
+	var report []RequiredEnvironment
+
+	for _, group := range service.DestinationGroups {
+		for _, dest := range group.Destinations {
+			env := dest.Github.Environment
+			if env == nil {
+				continue
+			}
+
+			idx := slices.IndexFunc(report, func(required RequiredEnvironment) bool {
+				return required.Name == env.Name
+			})
+			if idx < 0 {
+				report = append(report, RequiredEnvironment{Name: env.Name})
+				idx = len(report) - 1
+			}
+
+			// destinations sharing an environment agree on its protection, or leave it unspecified
+			if !reflect.ValueOf(env.Protection).IsZero() {
+				report[idx].Protection = env.Protection
+			}
+
+			report[idx].Destinations = append(report[idx].Destinations,
+				group.Name+"/"+v1alpha1.CoalesceSanitizeDestination(dest))
+		}
+	}
+
+	slices.SortFunc(report, func(a, b RequiredEnvironment) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return report
+}
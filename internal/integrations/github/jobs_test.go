@@ -0,0 +1,60 @@
+package github
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+)
+
+var _ = Describe("promotionExpression()", func() {
+	var argoCD v1alpha1.ArgoCD
+
+	BeforeEach(func() {
+		argoCD = v1alpha1.ArgoCD{
+			Source: v1alpha1.Source{Path: "podinfo"},
+		}
+	})
+
+	It("should promote the revision of the source", func() {
+		Expect(promotionExpression(argoCD)).To(Equal(`.spec.source.targetRevision = "${{ inputs.revision }}"`))
+	})
+
+	Context("a chart from a helm repository", func() {
+		BeforeEach(func() {
+			argoCD.Source = v1alpha1.Source{
+				Helm: &v1alpha1.HelmSource{RepoURL: "https://stefanprodan.github.io/podinfo", Chart: "podinfo", Version: "6.9.0"},
+			}
+		})
+
+		It("should promote the chart version", func() {
+			Expect(promotionExpression(argoCD)).To(Equal(`.spec.source.targetRevision = "6.9.0"`))
+		})
+	})
+
+	Context("a multi-source application", func() {
+		BeforeEach(func() {
+			argoCD.Source = v1alpha1.Source{}
+			argoCD.Sources = []v1alpha1.Source{
+				{
+					Helm: &v1alpha1.HelmSource{RepoURL: "https://stefanprodan.github.io/podinfo", Chart: "podinfo", Version: "6.9.0"},
+				},
+				{
+					RepoURL:        "https://github.com/example/shared-values.git",
+					TargetRevision: "main",
+					Ref:            "shared",
+				},
+				{
+					Path: "podinfo",
+					Ref:  "values",
+				},
+			}
+		})
+
+		It("should promote every promoted source, by index", func() {
+			Expect(promotionExpression(argoCD)).To(Equal(
+				`.spec.sources[0].targetRevision = "6.9.0" | .spec.sources[2].targetRevision = "${{ inputs.revision }}"`,
+			))
+		})
+	})
+})
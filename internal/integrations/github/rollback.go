@@ -0,0 +1,127 @@
+package github
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/cakehappens/gocto"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/constants"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+// rollbackCondition only rolls back once the promotion commit has reached the commit branch,
+// as the application may have been synced to it.
+const rollbackCondition = "failure() && steps." + gitCommitStepID + ".outputs.committed == 'true' && steps." + gitPushStepID + ".outcome == 'success'"
+
+// newRollbackSteps reverts the promotion commit, pushes the revert, and syncs the application back to the
+// previous targetRevision. The job has already failed, and the rollback does not change that.
+func newRollbackSteps(commitBranch string, argoCDSpec v1alpha1.ArgoCD, pushRetryLimit int) []gocto.Step {
+	steps := []gocto.Step{
+		{
+			Name: "rollback-revert",
+			If:   rollbackCondition,
+			Run: util.SprintfDedent(`
+					# destinations deploy one at a time (see the workflow concurrency),
+					# so the last commit to change the application file is the promotion commit
+					PROMOTION_COMMIT=$(git log -n 1 --format=%%H -- "${%[1]s}")
+					echo "reverting ${PROMOTION_COMMIT}: $(git log -n 1 --format=%%s "${PROMOTION_COMMIT}")"
+					git revert --no-edit "${PROMOTION_COMMIT}"
+				`, envNameArgoCDApplicationFile),
+		},
+	}
+
+	push := newGitPushStep(commitBranch, pushRetryLimit)
+	push.ID = ""
+	push.Name = "rollback-" + push.Name
+	push.If = rollbackCondition
+	steps = append(steps, push)
+
+	for _, step := range newArgoCDSyncSteps(argoCDSpec) {
+		step.Name = "rollback-" + step.Name
+		step.If = rollbackCondition
+		steps = append(steps, step)
+	}
+
+	return steps
+}
+
+// rollbackTarget is a destination the rollback workflow may redeploy, using its deployment workflow.
+type rollbackTarget struct {
+	group       string
+	destination v1alpha1.Destination
+	workflow    gocto.Workflow
+}
+
+// rollbackAllDestinations selects every destination of the group in the rollback workflow.
+const rollbackAllDestinations = "all"
+
+// newRollbackWorkflow lets an operator redeploy a previous revision of the service repository
+// to a whole destination group, or a single destination of it, by calling the deployment workflow
+// of each selected destination, just like a rollout would.
+// Charts from a helm repository are deployed at the chart version of the service definition.
+func newRollbackWorkflow(serviceName string, targets []rollbackTarget) (gocto.Workflow, error) {
+	var groups []string
+	destinations := []string{rollbackAllDestinations}
+
+	jobs := make(map[string]gocto.Job)
+	jobsOf := make(map[string]string)
+	for _, target := range targets {
+		destinationFriendlyName := v1alpha1.CoalesceSanitizeDestination(target.destination)
+
+		if !slices.Contains(groups, target.group) {
+			groups = append(groups, target.group)
+		}
+
+		if !slices.Contains(destinations, destinationFriendlyName) {
+			destinations = append(destinations, destinationFriendlyName)
+		}
+
+		name := target.group + "-" + destinationFriendlyName
+		if existing, ok := jobsOf[name]; ok {
+			return gocto.Workflow{}, fmt.Errorf("destination group %s: destination %s: rollback job %q is also the job of %s",
+				target.group, destinationFriendlyName, name, existing)
+		}
+		jobsOf[name] = "destination group " + target.group + ": destination " + destinationFriendlyName
+
+		job := newDeployGroupJob(name, target.workflow)
+		job.If = fmt.Sprintf("inputs.%[1]s == '%[2]s' && (inputs.%[3]s == '%[4]s' || inputs.%[3]s == '%[5]s')",
+			constants.WorkflowInputGroup, target.group,
+			constants.WorkflowInputDestination, rollbackAllDestinations, destinationFriendlyName)
+		job.Permissions = deployPermissions(target.destination)
+		job.With = map[string]string{
+			constants.WorkflowInputRevision: revisionExpression,
+		}
+		// rolling back is urgent, so it is not held back by the deploy windows
+		if _, ok := target.workflow.On.Call.Inputs[constants.WorkflowInputBypassDeployWindow]; ok {
+			job.With[constants.WorkflowInputBypassDeployWindow] = "${{ true }}"
+		}
+		jobs[job.Name] = job
+	}
+
+	dispatch := withRevisionDispatchInput(nil, "commit to roll back to", true)
+	dispatch.Inputs[constants.WorkflowInputGroup] = gocto.OnDispatchInput{
+		Description: "destination group to roll back",
+		Required:    true,
+		Type:        gocto.OnDispatchInputTypeChoice,
+		Options:     groups,
+	}
+	dispatch.Inputs[constants.WorkflowInputDestination] = gocto.OnDispatchInput{
+		Description: "destination of the group to roll back",
+		Required:    true,
+		Default:     rollbackAllDestinations,
+		Type:        gocto.OnDispatchInputTypeChoice,
+		Options:     destinations,
+	}
+
+	wf := gocto.Workflow{
+		Name: serviceName + "-" + constants.RollbackWorkflowName,
+		On: gocto.WorkflowOn{
+			Dispatch: dispatch,
+		},
+		Jobs: jobs,
+	}
+
+	return SetWorkflowFilenameWithAlveusPrefix(wf), nil
+}
@@ -0,0 +1,154 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/cakehappens/gocto"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/constants"
+)
+
+// RolloutMember is a service deployed by the rollout workflow, along with its top-level workflow.
+type RolloutMember struct {
+	Service v1alpha1.Service
+	Top     gocto.Workflow
+}
+
+// NewRolloutWorkflows deploys services which depend on each other, in order: each destination group of a service
+// needs the groups it needs within the service, and the group of the same name of every service it depends on.
+// Every service deploys the same revision. The rollout workflow is dispatched, or called by the trigger workflow
+// of each service, which is triggered by the push trigger of the service, keeping its own branches, paths and tags.
+// The top-level workflows of the services must no longer declare their push triggers.
+// The trigger workflows are returned first, and the rollout workflow last.
+func NewRolloutWorkflows(members []RolloutMember) ([]gocto.Workflow, error) {
+	if len(members) == 0 {
+		return nil, errors.New("no services to roll out")
+	}
+
+	commitBranch := members[0].Service.ArgoCD.Source.CommitBranch
+	for _, member := range members[1:] {
+		if member.Service.ArgoCD.Source.CommitBranch != commitBranch {
+			return nil, fmt.Errorf("services %s and %s commit promotions to different branches",
+				members[0].Service.Name, member.Service.Name)
+		}
+	}
+
+	wf := gocto.Workflow{
+		Name: constants.RolloutWorkflowName,
+		On: gocto.WorkflowOn{
+			Dispatch: withRevisionDispatchInput(nil,
+				"commit to promote, defaults to the commit that triggered the workflow", false),
+			Call: &gocto.OnCall{
+				Inputs: map[string]gocto.CallInput{
+					constants.WorkflowInputRevision: {
+						Description: "commit to promote, defaults to the commit that triggered the workflow",
+						Type:        gocto.CallInputTypeString,
+					},
+				},
+			},
+		},
+		Jobs: map[string]gocto.Job{
+			constants.ResolveRevisionJobName: newResolveRevisionJob(commitBranch),
+		},
+	}
+
+	rolloutJobName := func(serviceName, groupName string) string {
+		return serviceName + "-" + groupName
+	}
+
+	jobsOf := make(map[string]string)
+	for _, member := range members {
+		for _, group := range member.Service.DestinationGroups {
+			job, ok := member.Top.Jobs[group.Name]
+			if !ok {
+				return nil, fmt.Errorf("service %s: no job for destination group: %s", member.Service.Name, group.Name)
+			}
+
+			job.Needs = nil
+			for _, need := range member.Top.Jobs[group.Name].Needs {
+				if need == constants.ResolveRevisionJobName {
+					job.Needs = append(job.Needs, need)
+					continue
+				}
+
+				job.Needs = append(job.Needs, rolloutJobName(member.Service.Name, need))
+			}
+
+			for _, dependency := range member.Service.DependsOn {
+				dependencyMember := slices.IndexFunc(members, func(other RolloutMember) bool {
+					return other.Service.Name == dependency
+				})
+				if dependencyMember < 0 {
+					return nil, fmt.Errorf("service %s: depends on unknown service: %s", member.Service.Name, dependency)
+				}
+
+				// services without a group of the same name do not hold the group back
+				if _, ok := members[dependencyMember].Top.Jobs[group.Name]; ok && group.Name != constants.ResolveRevisionJobName {
+					if skipsOutsideDeployWindows(members[dependencyMember].Service, group.Name) {
+						return nil, fmt.Errorf("service %s: destination group %s waits for the group of service %s, "+
+							"so it must wait or fail outside of its deploy windows, rather than skip", member.Service.Name, group.Name, dependency)
+					}
+
+					job.Needs = append(job.Needs, rolloutJobName(dependency, group.Name))
+				}
+			}
+
+			name := rolloutJobName(member.Service.Name, group.Name)
+			if existing, ok := jobsOf[name]; ok || name == constants.ResolveRevisionJobName {
+				return nil, fmt.Errorf("service %s: destination group %s: job %q is also the job of %s",
+					member.Service.Name, group.Name, name, existing)
+			}
+			jobsOf[name] = "service " + member.Service.Name + ": destination group " + group.Name
+
+			job.Name = name
+			wf.Jobs[name] = job
+		}
+	}
+
+	wf = SetWorkflowFilenameWithAlveusPrefix(wf)
+
+	var workflows []gocto.Workflow
+	for _, member := range members {
+		if member.Top.On.Push != nil {
+			workflows = append(workflows, newRolloutTriggerWorkflow(member, wf))
+		}
+	}
+
+	return append(workflows, wf), nil
+}
+
+// skipsOutsideDeployWindows is true when the group of the service skips its promotions outside of its deploy windows,
+// which the groups waiting for it would not notice.
+func skipsOutsideDeployWindows(service v1alpha1.Service, groupName string) bool {
+	idx := slices.IndexFunc(service.DestinationGroups, func(group v1alpha1.DestinationGroup) bool {
+		return group.Name == groupName
+	})
+
+	return idx >= 0 && service.DestinationGroups[idx].DeployWindows != nil &&
+		service.DestinationGroups[idx].DeployWindows.Action == v1alpha1.DeployWindowActionSkip
+}
+
+// newRolloutTriggerWorkflow calls the rollout workflow on the push trigger of the service.
+// A push matching the triggers of several services rolls out once for each of them,
+// promoting the same revision again, which changes nothing.
+func newRolloutTriggerWorkflow(member RolloutMember, rollout gocto.Workflow) gocto.Workflow {
+	job := newDeployGroupJob(constants.RolloutWorkflowName, rollout)
+	for _, rolloutJob := range rollout.Jobs {
+		job.Permissions = mergePermissions(job.Permissions, rolloutJob.Permissions)
+	}
+
+	wf := gocto.Workflow{
+		Name: constants.RolloutWorkflowName + "-" + member.Service.Name,
+		On: gocto.WorkflowOn{
+			Push: member.Top.On.Push,
+		},
+		Jobs: map[string]gocto.Job{
+			constants.RolloutWorkflowName: job,
+		},
+	}
+
+	return SetWorkflowFilenameWithAlveusPrefix(wf)
+}
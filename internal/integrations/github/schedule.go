@@ -0,0 +1,43 @@
+package github
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/cakehappens/gocto"
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/parser"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+// MarshalWorkflow renders the workflow, triggered on the schedule, if any.
+// gocto.OnSchedule cannot express cron expressions, so the schedule is merged into the rendered "on" triggers.
+func MarshalWorkflow(wf gocto.Workflow, schedule []v1alpha1.Schedule) ([]byte, error) {
+	contents, err := util.YamlMarshalWithOptions(wf)
+	if err != nil || len(schedule) == 0 {
+		return contents, err
+	}
+
+	trigger, err := util.YamlMarshalWithOptions(map[string][]v1alpha1.Schedule{"schedule": schedule})
+	if err != nil {
+		return nil, fmt.Errorf("marshalling schedule: %w", err)
+	}
+
+	file, err := parser.ParseBytes(contents, 0)
+	if err != nil {
+		return nil, fmt.Errorf("parsing workflow: %w", err)
+	}
+
+	on, err := yaml.PathString("$.on")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := on.MergeFromReader(file, bytes.NewReader(trigger)); err != nil {
+		return nil, fmt.Errorf("merging schedule: %w", err)
+	}
+
+	return []byte(file.String() + "\n"), nil
+}
@@ -0,0 +1,170 @@
+package github
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+
+	"github.com/cakehappens/gocto"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+const (
+	EnvNameToolVersion       = "TOOL_VERSION"
+	EnvNameToolDir           = "TOOL_DIR"
+	EnvNameToolSHA256Prefix  = "TOOL_SHA256_"
+	toolCacheDirectoryFormat = "${{ runner.tool_cache }}/alveus/%s/%s"
+)
+
+type toolRelease struct {
+	name string
+	// downloadURLFormat is formatted with the version & architecture of the binary.
+	downloadURLFormat string
+}
+
+var (
+	argoCDRelease = toolRelease{
+		name:              "argocd",
+		downloadURLFormat: "https://github.com/argoproj/argo-cd/releases/download/%s/argocd-linux-%s",
+	}
+	yqRelease = toolRelease{
+		name:              "yq",
+		downloadURLFormat: "https://github.com/mikefarah/yq/releases/download/%s/yq_linux_%s",
+	}
+)
+
+// defaultYqVersion is the version of yq installed when none is declared.
+// It is built from source, so the module is verified against the Go checksum database rather than a declared checksum.
+const defaultYqVersion = "v4.44.3"
+
+// newToolInstallSteps installs the CLIs used by the deploy job.
+// Declared tools are cached by version & checksums, and verified against their checksums on every run,
+// so that a tampered cache is never used.
+func newToolInstallSteps(github v1alpha1.Github) []gocto.Step {
+	var steps []gocto.Step
+
+	if github.Tools.ArgoCD != nil {
+		steps = append(steps, newToolInstallStepsFor(argoCDRelease, *github.Tools.ArgoCD)...)
+	}
+
+	if github.Tools.Yq != nil {
+		steps = append(steps, newToolInstallStepsFor(yqRelease, *github.Tools.Yq)...)
+	} else if github.Container == nil {
+		steps = append(steps, newDefaultYqInstallSteps()...)
+	}
+
+	return steps
+}
+
+func newToolInstallStepsFor(release toolRelease, tool v1alpha1.Tool) []gocto.Step {
+	toolDir := fmt.Sprintf(toolCacheDirectoryFormat, release.name, tool.Version)
+
+	env := map[string]string{
+		EnvNameToolVersion: tool.Version,
+		EnvNameToolDir:     toolDir,
+	}
+
+	for arch, checksum := range tool.SHA256 {
+		env[EnvNameToolSHA256Prefix+arch] = checksum
+	}
+
+	return []gocto.Step{
+		{
+			Name: fmt.Sprintf("cache-%s", release.name),
+			Uses: "actions/cache@v4",
+			With: map[string]any{
+				"path": toolDir,
+				"key": fmt.Sprintf("alveus-%s-%s-%s-${{ runner.os }}-${{ runner.arch }}",
+					release.name, tool.Version, checksumsDigest(tool.SHA256)),
+			},
+		},
+		{
+			Name: fmt.Sprintf("install-%s", release.name),
+			Env:  env,
+			Run: util.SprintfDedent(`
+					case "${RUNNER_ARCH}" in
+						X64) ARCH=amd64 ;;
+						ARM64) ARCH=arm64 ;;
+						*)
+							echo "::error::unsupported runner architecture: ${RUNNER_ARCH}"
+							exit 1
+							;;
+					esac
+
+					CHECKSUM_VAR="%[3]s${ARCH}"
+					CHECKSUM="${!CHECKSUM_VAR:-}"
+					if [[ -z "${CHECKSUM}" ]]; then
+						echo "::error::no sha256 checksum declared for %[2]s ${%[4]s} (${ARCH})"
+						exit 1
+					fi
+
+					if [[ ! -x "${%[1]s}/%[2]s" ]]; then
+						DOWNLOAD="${RUNNER_TEMP}/%[2]s"
+						curl -sSfL -o "${DOWNLOAD}" "%[5]s"
+						mkdir -p "${%[1]s}"
+						install -m 0755 "${DOWNLOAD}" "${%[1]s}/%[2]s"
+					fi
+
+					echo "${CHECKSUM}  ${%[1]s}/%[2]s" | sha256sum --check --strict
+					echo "${%[1]s}" >> "${GITHUB_PATH}"
+				`, EnvNameToolDir, release.name, EnvNameToolSHA256Prefix, EnvNameToolVersion,
+				fmt.Sprintf(release.downloadURLFormat, "${"+EnvNameToolVersion+"}", "${ARCH}")),
+		},
+	}
+}
+
+// newDefaultYqInstallSteps installs the default version of yq, built with the Go toolchain of the runner.
+// The cached binary is checked to be built from the expected module version on every run.
+func newDefaultYqInstallSteps() []gocto.Step {
+	toolDir := fmt.Sprintf(toolCacheDirectoryFormat, yqRelease.name, defaultYqVersion)
+
+	return []gocto.Step{
+		{
+			Name: fmt.Sprintf("cache-%s", yqRelease.name),
+			Uses: "actions/cache@v4",
+			With: map[string]any{
+				"path": toolDir,
+				"key":  fmt.Sprintf("alveus-%s-%s-source-${{ runner.os }}-${{ runner.arch }}", yqRelease.name, defaultYqVersion),
+			},
+		},
+		{
+			Name: fmt.Sprintf("install-%s", yqRelease.name),
+			Env: map[string]string{
+				EnvNameToolVersion: defaultYqVersion,
+				EnvNameToolDir:     toolDir,
+			},
+			Run: util.SprintfDedent(`
+					if [[ ! -x "${%[1]s}/yq" ]]; then
+						GOBIN="${%[1]s}" GOFLAGS=-mod=mod go install "github.com/mikefarah/yq/v4@${%[2]s}"
+					fi
+
+					if ! go version -m "${%[1]s}/yq" | grep -qE "^[[:space:]]+mod[[:space:]]+github.com/mikefarah/yq/v4[[:space:]]+${%[2]s}[[:space:]]"; then
+						echo "::error::${%[1]s}/yq is not built from github.com/mikefarah/yq/v4@${%[2]s}"
+						exit 1
+					fi
+
+					echo "${%[1]s}" >> "${GITHUB_PATH}"
+				`, EnvNameToolDir, EnvNameToolVersion),
+		},
+	}
+}
+
+// checksumsDigest is a short digest of the declared checksums, so that the cache of a tool is invalidated
+// whenever its checksums change.
+func checksumsDigest(checksums map[string]string) string {
+	archs := make([]string, 0, len(checksums))
+	for arch := range checksums {
+		archs = append(archs, arch)
+	}
+	slices.Sort(archs)
+
+	hash := sha256.New()
+	for _, arch := range archs {
+		fmt.Fprintf(hash, "%s=%s\n", arch, checksums[arch])
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))[:12]
+}
@@ -0,0 +1,142 @@
+package github
+
+import (
+	"maps"
+	"strings"
+
+	"github.com/cakehappens/gocto"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+)
+
+var _ = Describe("newToolInstallSteps()", func() {
+	var (
+		github v1alpha1.Github
+
+		steps []gocto.Step
+	)
+
+	stepNamed := func(name string) gocto.Step {
+		for _, step := range steps {
+			if step.Name == name {
+				return step
+			}
+		}
+
+		Fail("no step named " + name)
+		return gocto.Step{}
+	}
+
+	stepNames := func() []string {
+		var names []string
+		for _, step := range steps {
+			names = append(names, step.Name)
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		github = v1alpha1.Github{}
+	})
+
+	JustBeforeEach(func() {
+		steps = newToolInstallSteps(github)
+	})
+
+	Context("a declared tool", func() {
+		BeforeEach(func() {
+			github.Tools.ArgoCD = &v1alpha1.Tool{
+				Version: "v3.1.0",
+				SHA256: map[string]string{
+					"amd64": strings.Repeat("a", 64),
+					"arm64": strings.Repeat("b", 64),
+				},
+			}
+		})
+
+		It("should cache the tool by version & checksums", func() {
+			key := stepNamed("cache-argocd").With["key"]
+			Expect(key).To(MatchRegexp(`^alveus-argocd-v3\.1\.0-[a-f0-9]{12}-\$\{\{ runner\.os \}\}-\$\{\{ runner\.arch \}\}$`))
+		})
+
+		It("should pass the checksums by architecture", func() {
+			Expect(stepNamed("install-argocd").Env).To(Equal(map[string]string{
+				EnvNameToolVersion:                "v3.1.0",
+				EnvNameToolDir:                    "${{ runner.tool_cache }}/alveus/argocd/v3.1.0",
+				EnvNameToolSHA256Prefix + "amd64": strings.Repeat("a", 64),
+				EnvNameToolSHA256Prefix + "arm64": strings.Repeat("b", 64),
+			}))
+		})
+
+		It("should verify the checksum of the cached binary too", func() {
+			run := stepNamed("install-argocd").Run
+			download := strings.Index(run, "curl ")
+			check := strings.Index(run, "sha256sum --check --strict")
+
+			Expect(download).To(BeNumerically(">=", 0))
+			Expect(check).To(BeNumerically(">", download))
+			Expect(run[download:check]).To(ContainSubstring("\nfi\n"))
+		})
+
+		It("should still install the default yq", func() {
+			Expect(stepNames()).To(Equal([]string{"cache-argocd", "install-argocd", "cache-yq", "install-yq"}))
+		})
+	})
+
+	Context("no declared yq", func() {
+		It("should build yq from source", func() {
+			Expect(stepNames()).To(Equal([]string{"cache-yq", "install-yq"}))
+			Expect(stepNamed("cache-yq").With["key"]).To(Equal(
+				"alveus-yq-" + defaultYqVersion + "-source-${{ runner.os }}-${{ runner.arch }}",
+			))
+			Expect(stepNamed("install-yq").Run).To(ContainSubstring(`go install "github.com/mikefarah/yq/v4@${TOOL_VERSION}"`))
+		})
+
+		Context("with a container", func() {
+			BeforeEach(func() {
+				github.Container = &gocto.Container{Image: "example.com/deployer:latest"}
+			})
+
+			It("should leave yq to the image", func() {
+				Expect(steps).To(BeEmpty())
+			})
+		})
+	})
+})
+
+var _ = Describe("checksumsDigest()", func() {
+	checksums := map[string]string{
+		"amd64": strings.Repeat("a", 64),
+		"arm64": strings.Repeat("b", 64),
+	}
+
+	It("should be short", func() {
+		Expect(checksumsDigest(checksums)).To(MatchRegexp(`^[a-f0-9]{12}$`))
+	})
+
+	It("should change with the checksums", func() {
+		changed := map[string]string{
+			"amd64": strings.Repeat("a", 64),
+			"arm64": strings.Repeat("c", 64),
+		}
+
+		Expect(checksumsDigest(changed)).NotTo(Equal(checksumsDigest(checksums)))
+	})
+
+	It("should tell apart checksums swapped between architectures", func() {
+		swapped := map[string]string{
+			"amd64": strings.Repeat("b", 64),
+			"arm64": strings.Repeat("a", 64),
+		}
+
+		Expect(checksumsDigest(swapped)).NotTo(Equal(checksumsDigest(checksums)))
+	})
+
+	It("should be stable", func() {
+		for range 10 {
+			Expect(checksumsDigest(checksums)).To(Equal(checksumsDigest(maps.Clone(checksums))))
+		}
+	})
+})
@@ -0,0 +1,71 @@
+package github
+
+import (
+	"github.com/cakehappens/gocto"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+// soakPollIntervalSeconds is how often the health of the application is checked while soaking.
+const soakPollIntervalSeconds = 30
+
+// newVerificationSteps verifies the synced application, failing the deploy job (and so the rollout)
+// unless it becomes healthy, remains so for the soak duration, and passes the checks.
+// The application may progress (e.g. scale) while soaking, but must be healthy once the soak ends.
+func newVerificationSteps(verification *v1alpha1.Verification, extraArgoCDArgs string) []gocto.Step {
+	if verification == nil {
+		return nil
+	}
+
+	steps := []gocto.Step{
+		{
+			Name: "argocd-wait-healthy",
+			Run: util.SprintfDedent(`
+					APP_NAME=$(yq '.metadata.name' "${%s}")
+					echo "waiting for ${APP_NAME} to become healthy"
+					argocd app wait \
+						"${APP_NAME}" \
+						%s \
+						--health \
+						--timeout %d \
+						;
+				`, envNameArgoCDApplicationFile, extraArgoCDArgs, *verification.HealthTimeoutSeconds),
+		},
+	}
+
+	if verification.SoakSeconds > 0 {
+		steps = append(steps, gocto.Step{
+			Name: "soak",
+			Run: util.SprintfDedent(`
+					APP_NAME=$(yq '.metadata.name' "${%[1]s}")
+					SOAK_END=$(( $(date +%%s) + %[2]d ))
+					while :; do
+						HEALTH=$(argocd app get "${APP_NAME}" %[3]s -o json | jq -r '.status.health.status')
+						echo "health of ${APP_NAME}: ${HEALTH}"
+
+						if (( $(date +%%s) >= SOAK_END )); then
+							if [[ "${HEALTH}" != "Healthy" ]]; then
+								echo "::error::${APP_NAME} is ${HEALTH} at the end of the soak"
+								exit 1
+							fi
+							break
+						fi
+
+						case "${HEALTH}" in
+							Healthy|Progressing) ;;
+							*)
+								echo "::error::${APP_NAME} became ${HEALTH} while soaking"
+								exit 1
+								;;
+						esac
+						sleep %[4]d
+					done
+				`, envNameArgoCDApplicationFile, verification.SoakSeconds, extraArgoCDArgs, soakPollIntervalSeconds),
+		})
+	}
+
+	steps = append(steps, verification.Checks...)
+
+	return steps
+}
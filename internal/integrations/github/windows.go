@@ -0,0 +1,217 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cakehappens/gocto"
+
+	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
+	"github.com/wmcnamee-coreweave/alveus/internal/constants"
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+const (
+	// deployWindowOutputOpen is the output of the deploy window job, true when the destination may be promoted.
+	deployWindowOutputOpen = "open"
+	// deployWindowPollIntervalSeconds is how often the wait action checks whether a window has opened.
+	deployWindowPollIntervalSeconds = 60
+)
+
+// deployWindowCheckScript defines window_open, which is true when the current minute is within an allowed window
+// (if any is declared), and outside of every blackout window.
+// A window is open when the last minute its cron expression matched is within the duration of the window,
+// evaluated in the time zone of the window, with the usual cron semantics of the day-of-month & day-of-week fields.
+// The last match is found day by day, so that checking a window only takes a few calls to date.
+const deployWindowCheckScript = `
+	cron_field_matches() {
+		local value=$((10#$1)) field=$2 low=$3 high=$4 part values step from to
+		IFS=, read -ra parts <<< "${field}"
+		for part in "${parts[@]}"; do
+			values=${part%%/*}
+			step=1
+			[[ "${part}" == */* ]] && step=${part#*/}
+			if [[ "${values}" == "*" ]]; then
+				from=${low}
+				to=${high}
+			elif [[ "${values}" == *-* ]]; then
+				from=${values%-*}
+				to=${values#*-}
+			else
+				from=${values}
+				to=${values}
+				[[ "${part}" == */* ]] && to=${high}
+			fi
+			if (( value >= 10#${from} && value <= 10#${to} && (value - 10#${from}) % 10#${step} == 0 )); then
+				return 0
+			fi
+		done
+		return 1
+	}
+
+	cron_day_matches() {
+		local dom_field=$1 month_field=$2 dow_field=$3 dom=$4 month=$5 dow=$6
+		cron_field_matches "${month}" "${month_field}" 1 12 || return 1
+
+		local dom_matches=false dow_matches=false
+		cron_field_matches "${dom}" "${dom_field}" 1 31 && dom_matches=true
+		if cron_field_matches "${dow}" "${dow_field}" 0 7 || { (( dow == 0 )) && cron_field_matches 7 "${dow_field}" 0 7; }; then
+			dow_matches=true
+		fi
+
+		if [[ "${dom_field}" != \** && "${dow_field}" != \** ]]; then
+			[[ "${dom_matches}" == true || "${dow_matches}" == true ]]
+		else
+			[[ "${dom_matches}" == true && "${dow_matches}" == true ]]
+		fi
+	}
+
+	last_cron_match() {
+		local now=$1 cron=$2 tz=$3 days=$4
+		local -a fields
+		read -ra fields <<< "${cron}"
+
+		local noon day date dom month dow hour minute last_hour last_minute time match
+		read -r date last_hour last_minute <<< "$(TZ="${tz}" date -d "@${now}" '+%F %H %M')"
+		noon=$(TZ="${tz}" date -d "${date} 12:00" +%s)
+
+		for (( day = 0; day <= days; day++ )); do
+			read -r date dom month dow <<< "$(TZ="${tz}" date -d "@$(( noon - day * 86400 ))" '+%F %d %m %w')"
+			if (( day > 0 )); then
+				last_hour=23
+				last_minute=59
+			fi
+			cron_day_matches "${fields[2]}" "${fields[3]}" "${fields[4]}" "${dom}" "${month}" "${dow}" || continue
+
+			for (( hour = 10#${last_hour}; hour >= 0; hour-- )); do
+				cron_field_matches "${hour}" "${fields[1]}" 0 23 || continue
+				for (( minute = (hour == 10#${last_hour} ? 10#${last_minute} : 59); minute >= 0; minute-- )); do
+					cron_field_matches "${minute}" "${fields[0]}" 0 59 || continue
+					printf -v time '%02d:%02d' "${hour}" "${minute}"
+					# minutes skipped by a daylight saving time change never happened
+					if match=$(TZ="${tz}" date -d "${date} ${time}" +%s 2>/dev/null); then
+						echo "${match}"
+						return 0
+					fi
+				done
+			done
+		done
+		return 1
+	}
+
+	within_any_window() {
+		local now=$1 window minutes tz cron match
+		shift
+		for window in "$@"; do
+			read -r minutes tz cron <<< "${window}"
+			if match=$(last_cron_match "${now}" "${cron}" "${tz}" $(( minutes / 1440 + 1 ))) && (( now - match < minutes * 60 )); then
+				return 0
+			fi
+		done
+		return 1
+	}
+
+	window_open() {
+		local now=$(( $(date +%s) / 60 * 60 ))
+		if (( ${#ALLOWED_WINDOWS[@]} > 0 )) && ! within_any_window "${now}" "${ALLOWED_WINDOWS[@]}"; then
+			echo "outside of the allowed deploy windows"
+			return 1
+		fi
+		if within_any_window "${now}" "${BLACKOUT_WINDOWS[@]}"; then
+			echo "within a blackout window"
+			return 1
+		fi
+		return 0
+	}
+`
+
+// newDeployWindowJob checks the deploy windows of the destination before it is promoted,
+// and, outside of the windows, waits for a window to open, skips the promotion, or fails.
+// The deploy job only runs when the job reports the window is open, as it always does when the windows are bypassed,
+// e.g. to roll back.
+func newDeployWindowJob(windows v1alpha1.DeployWindows) gocto.Job {
+	const EnvNameBypass = "BYPASS_DEPLOY_WINDOW"
+
+	var closed string
+	switch windows.Action {
+	case v1alpha1.DeployWindowActionWait:
+		closed = util.SprintfDedent(`
+				if (( $(date +%%s) >= DEADLINE )); then
+					echo "::error::no deploy window opened within %[1]d minute(s)"
+					exit 1
+				fi
+				sleep %[2]d
+			`, *windows.MaxWaitMinutes, deployWindowPollIntervalSeconds)
+	case v1alpha1.DeployWindowActionSkip:
+		closed = util.SprintfDedent(`
+				echo "::notice::skipping the promotion outside of the deploy windows"
+				echo "%s=false" >> "${GITHUB_OUTPUT}"
+				exit 0
+			`, deployWindowOutputOpen)
+	default:
+		closed = `echo "::error::promotion is not allowed outside of the deploy windows"` + "\n" + "exit 1"
+	}
+
+	run := util.Dedent(deployWindowCheckScript) + "\n\n" + util.SprintfDedent(`
+			if [[ "${%[6]s}" == "true" ]]; then
+				echo "::warning::bypassing the deploy windows"
+				echo "%[5]s=true" >> "${GITHUB_OUTPUT}"
+				exit 0
+			fi
+
+			ALLOWED_WINDOWS=(%[1]s)
+			BLACKOUT_WINDOWS=(%[2]s)
+			DEADLINE=$(( $(date +%%s) + %[3]d * 60 ))
+
+			until window_open; do
+			%[4]s
+			done
+
+			echo "%[5]s=true" >> "${GITHUB_OUTPUT}"
+		`, formatTimeWindows(windows.Allowed), formatTimeWindows(windows.Blackouts), *windows.MaxWaitMinutes,
+		indent(closed, "  "), deployWindowOutputOpen, EnvNameBypass)
+
+	return gocto.Job{
+		Name:   constants.DeployWindowJobName,
+		RunsOn: []string{"ubuntu-latest"},
+		Defaults: gocto.Defaults{
+			Run: gocto.DefaultsRun{
+				Shell: gocto.ShellBash,
+			},
+		},
+		TimeoutMinutes: *windows.MaxWaitMinutes + 10,
+		Outputs: map[string]string{
+			deployWindowOutputOpen: fmt.Sprintf("${{ steps.check.outputs.%s }}", deployWindowOutputOpen),
+		},
+		Steps: []gocto.Step{
+			{
+				ID:   "check",
+				Name: "check-deploy-window",
+				Env: map[string]string{
+					EnvNameBypass: fmt.Sprintf("${{ inputs.%s }}", constants.WorkflowInputBypassDeployWindow),
+				},
+				Run: run,
+			},
+		},
+	}
+}
+
+// formatTimeWindows formats the windows as the elements of a bash array, each "<minutes> <time zone> <cron>".
+func formatTimeWindows(windows []v1alpha1.TimeWindow) string {
+	elements := make([]string, 0, len(windows))
+	for _, window := range windows {
+		elements = append(elements, fmt.Sprintf("'%d %s %s'",
+			window.DurationMinutes(), util.CoalesceStrings(window.TimeZone, "UTC"), window.Cron))
+	}
+
+	return strings.Join(elements, " ")
+}
+
+func indent(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for idx, line := range lines {
+		lines[idx] = prefix + line
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,140 @@
+package github
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/wmcnamee-coreweave/alveus/internal/util"
+)
+
+var _ = Describe("deployWindowCheckScript", func() {
+	var (
+		utc     *time.Location
+		newYork *time.Location
+		kolkata *time.Location
+	)
+
+	BeforeEach(func() {
+		var err error
+		utc = time.UTC
+		newYork, err = time.LoadLocation("America/New_York")
+		Expect(err).NotTo(HaveOccurred())
+		kolkata, err = time.LoadLocation("Asia/Kolkata")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	// run runs the script under bash, followed by the commands, with `date +%s` returning now.
+	run := func(now time.Time, commands string) string {
+		script := util.Dedent(deployWindowCheckScript) + "\n" +
+			fmt.Sprintf(`date() { if [[ "$*" == "+%%s" ]]; then echo %d; else command date "$@"; fi; }`, now.Unix()) + "\n" +
+			commands
+
+		out, err := exec.Command("bash", "-c", script).Output()
+		Expect(err).NotTo(HaveOccurred())
+		return strings.TrimSpace(string(out))
+	}
+
+	lastCronMatch := func(now time.Time, cron, tz string, days int) string {
+		return run(now, fmt.Sprintf(`last_cron_match %d '%s' %s %d || echo none`, now.Unix(), cron, tz, days))
+	}
+
+	epoch := func(t time.Time) string {
+		return fmt.Sprintf("%d", t.Unix())
+	}
+
+	Describe("last_cron_match", func() {
+		It("should find the last weekday of a range", func() {
+			saturday := time.Date(2026, 3, 7, 12, 0, 0, 0, utc)
+			Expect(lastCronMatch(saturday, "0 9 * * 1-5", "UTC", 2)).To(Equal(epoch(time.Date(2026, 3, 6, 9, 0, 0, 0, utc))))
+		})
+
+		It("should not look back further than the given days", func() {
+			saturday := time.Date(2026, 3, 7, 12, 0, 0, 0, utc)
+			Expect(lastCronMatch(saturday, "0 9 * * 1-5", "UTC", 0)).To(Equal("none"))
+		})
+
+		It("should find the current minute", func() {
+			now := time.Date(2026, 3, 6, 9, 0, 0, 0, utc)
+			Expect(lastCronMatch(now, "0 9 * * *", "UTC", 1)).To(Equal(epoch(now)))
+		})
+
+		It("should step through the values of a field", func() {
+			now := time.Date(2026, 3, 6, 10, 7, 0, 0, utc)
+			Expect(lastCronMatch(now, "*/15 * * * *", "UTC", 1)).To(Equal(epoch(time.Date(2026, 3, 6, 10, 0, 0, 0, utc))))
+
+			now = time.Date(2026, 3, 6, 8, 3, 0, 0, utc)
+			Expect(lastCronMatch(now, "5/20 8-10 * * *", "UTC", 1)).To(Equal(epoch(time.Date(2026, 3, 5, 10, 45, 0, 0, utc))))
+		})
+
+		It("should match either the day of the month or the day of the week when both are restricted", func() {
+			thursday := time.Date(2026, 5, 14, 12, 0, 0, 0, utc)
+			Expect(lastCronMatch(thursday, "0 0 13 * 5", "UTC", 7)).To(Equal(epoch(time.Date(2026, 5, 13, 0, 0, 0, 0, utc))))
+
+			saturday := time.Date(2026, 5, 16, 12, 0, 0, 0, utc)
+			Expect(lastCronMatch(saturday, "0 0 13 * 5", "UTC", 7)).To(Equal(epoch(time.Date(2026, 5, 15, 0, 0, 0, 0, utc))))
+		})
+
+		It("should match both the day of the month and the day of the week when only one is restricted", func() {
+			thursday := time.Date(2026, 5, 14, 12, 0, 0, 0, utc)
+			Expect(lastCronMatch(thursday, "0 0 * * 5", "UTC", 7)).To(Equal(epoch(time.Date(2026, 5, 8, 0, 0, 0, 0, utc))))
+			Expect(lastCronMatch(thursday, "0 0 * * 7", "UTC", 7)).To(Equal(epoch(time.Date(2026, 5, 10, 0, 0, 0, 0, utc))))
+		})
+
+		It("should evaluate the cron expression in the time zone", func() {
+			now := time.Date(2026, 1, 15, 10, 0, 0, 0, newYork)
+			Expect(lastCronMatch(now, "0 9 * * *", "America/New_York", 1)).To(Equal(epoch(time.Date(2026, 1, 15, 9, 0, 0, 0, newYork))))
+
+			now = time.Date(2026, 1, 15, 8, 30, 0, 0, kolkata)
+			Expect(lastCronMatch(now, "0 9 * * *", "Asia/Kolkata", 1)).To(Equal(epoch(time.Date(2026, 1, 14, 9, 0, 0, 0, kolkata))))
+		})
+
+		It("should skip the minutes skipped by a daylight saving time change", func() {
+			now := time.Date(2026, 3, 8, 12, 0, 0, 0, newYork)
+			Expect(lastCronMatch(now, "30 2 * * *", "America/New_York", 1)).To(Equal(epoch(time.Date(2026, 3, 7, 2, 30, 0, 0, newYork))))
+			Expect(lastCronMatch(now, "0 3 * * *", "America/New_York", 1)).To(Equal(epoch(time.Date(2026, 3, 8, 3, 0, 0, 0, newYork))))
+		})
+	})
+
+	Describe("window_open", func() {
+		windowOpen := func(now time.Time, allowed, blackouts string) string {
+			return run(now, fmt.Sprintf(`
+				ALLOWED_WINDOWS=(%s)
+				BLACKOUT_WINDOWS=(%s)
+				if window_open; then echo open; fi
+			`, allowed, blackouts))
+		}
+
+		It("should be open without any window", func() {
+			Expect(windowOpen(time.Date(2026, 3, 7, 1, 0, 0, 0, utc), "", "")).To(Equal("open"))
+		})
+
+		It("should be open within a window spanning midnight", func() {
+			allowed := `'240 UTC 0 22 * * *'`
+
+			Expect(windowOpen(time.Date(2026, 3, 6, 22, 0, 0, 0, utc), allowed, "")).To(Equal("open"))
+			Expect(windowOpen(time.Date(2026, 3, 7, 1, 59, 30, 0, utc), allowed, "")).To(Equal("open"))
+			Expect(windowOpen(time.Date(2026, 3, 7, 2, 0, 0, 0, utc), allowed, "")).To(Equal("outside of the allowed deploy windows"))
+			Expect(windowOpen(time.Date(2026, 3, 6, 21, 59, 0, 0, utc), allowed, "")).To(Equal("outside of the allowed deploy windows"))
+		})
+
+		It("should be open within any of the allowed windows", func() {
+			allowed := `'60 UTC 0 9 * * 1-5' '60 America/New_York 0 9 * * 1-5'`
+
+			Expect(windowOpen(time.Date(2026, 3, 6, 14, 30, 0, 0, utc), allowed, "")).To(Equal("open"))
+			Expect(windowOpen(time.Date(2026, 3, 7, 14, 30, 0, 0, utc), allowed, "")).To(Equal("outside of the allowed deploy windows"))
+		})
+
+		It("should be closed within a blackout window, even within an allowed window", func() {
+			allowed := `'1440 UTC 0 0 * * *'`
+			blackouts := `'2880 Europe/Berlin 0 0 24 12 *'`
+
+			Expect(windowOpen(time.Date(2026, 12, 25, 12, 0, 0, 0, utc), allowed, blackouts)).To(Equal("within a blackout window"))
+			Expect(windowOpen(time.Date(2026, 12, 25, 23, 0, 0, 0, utc), allowed, blackouts)).To(Equal("open"))
+		})
+	})
+})
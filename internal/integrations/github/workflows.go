@@ -2,8 +2,9 @@ package github
 
 import (
 	"fmt"
+	"maps"
+
 	"github.com/cakehappens/gocto"
-	"github.com/goforj/godump"
 
 	"github.com/wmcnamee-coreweave/alveus/api/v1alpha1"
 	"github.com/wmcnamee-coreweave/alveus/internal/constants"
@@ -19,96 +20,145 @@ func SetWorkflowFilenameWithAlveusPrefix(w gocto.Workflow) gocto.Workflow {
 	return w
 }
 
-func NewWorkflows(service v1alpha1.Service, apps argocd.ApplicationRepository) []gocto.Workflow {
+func NewWorkflows(service v1alpha1.Service, apps argocd.ApplicationRepository) ([]gocto.Workflow, error) {
 	var workflows []gocto.Workflow
 
 	top := gocto.Workflow{
 		Name: service.Name,
 		On:   service.Github.On,
-		Jobs: make(map[string]gocto.Job),
+		Jobs: map[string]gocto.Job{
+			constants.ResolveRevisionJobName: newResolveRevisionJob(service.ArgoCD.Source.CommitBranch),
+		},
 	}
 
+	top.On.Dispatch = withRevisionDispatchInput(top.On.Dispatch,
+		"commit to promote, defaults to the commit that triggered the workflow", false)
+
 	top = SetWorkflowFilenameWithAlveusPrefix(top)
 
-	var prevGroupJob *gocto.Job
+	var rollbackTargets []rollbackTarget
+
+	needs := service.DestinationGroups.Needs()
 	for _, dg := range service.DestinationGroups {
-		dgWf, subWfs := newDeploymentGroupWorkflows(newDeploymentGroupWorkflowInput{
-			namePrefix:           service.Name,
+		if dg.Name == constants.ResolveRevisionJobName || dg.Name == constants.RollbackWorkflowName {
+			return nil, fmt.Errorf("destination group name is reserved: %s", dg.Name)
+		}
+
+		dgWf, subWfs, err := newDeploymentGroupWorkflows(newDeploymentGroupWorkflowInput{
+			serviceName:          service.Name,
 			group:                dg,
 			checkoutCommitBranch: service.ArgoCD.Source.CommitBranch,
 			apps:                 apps,
 		})
+		if err != nil {
+			return nil, fmt.Errorf("destination group: %s: %w", dg.Name, err)
+		}
 		workflows = append(workflows, dgWf)
 		workflows = append(workflows, subWfs...)
 
+		for idx, dest := range dg.Destinations {
+			rollbackTargets = append(rollbackTargets, rollbackTarget{
+				group:       dg.Name,
+				destination: dest,
+				workflow:    subWfs[idx],
+			})
+		}
+
 		job := newDeployGroupJob(dg.Name, dgWf)
-		if prevGroupJob != nil {
-			job.Needs = []string{prevGroupJob.Name}
+		for _, dest := range dg.Destinations {
+			job.Permissions = mergePermissions(job.Permissions, deployPermissions(dest))
+		}
+		job.Needs = append([]string{constants.ResolveRevisionJobName}, needs[dg.Name]...)
+		job.With = map[string]string{
+			constants.WorkflowInputRevision: fmt.Sprintf("${{ needs.%s.outputs.%s }}",
+				constants.ResolveRevisionJobName, constants.WorkflowInputRevision),
 		}
-		prevGroupJob = &job
 		top.Jobs[dg.Name] = job
 	}
 
-	workflows = append(workflows, top)
+	rollback, err := newRollbackWorkflow(service.Name, rollbackTargets)
+	if err != nil {
+		return nil, err
+	}
+
+	workflows = append(workflows, rollback, top)
 
-	return workflows
+	return workflows, nil
 }
 
 type newDeploymentGroupWorkflowInput struct {
-	namePrefix           string
+	serviceName          string
 	group                v1alpha1.DestinationGroup
 	checkoutCommitBranch string
 	apps                 argocd.ApplicationRepository
 }
 
-func newDeploymentGroupWorkflows(input newDeploymentGroupWorkflowInput) (gocto.Workflow, []gocto.Workflow) {
+func newDeploymentGroupWorkflows(input newDeploymentGroupWorkflowInput) (gocto.Workflow, []gocto.Workflow, error) {
 	var subWorkflows []gocto.Workflow
 
-	namePrefix := input.namePrefix + "-" + input.group.Name
+	namePrefix := input.serviceName + "-" + input.group.Name
 
 	groupWf := gocto.Workflow{
 		Name: namePrefix,
-		On: gocto.WorkflowOn{
-			Dispatch: &gocto.OnDispatch{},
-			Call:     &gocto.OnCall{},
-		},
+		On:   newRevisionWorkflowOn(),
 		Jobs: make(map[string]gocto.Job),
 	}
 	groupWf = SetWorkflowFilenameWithAlveusPrefix(groupWf)
 
 	for _, dest := range input.group.Destinations {
-		wf := newDeploymentWorkflow(newDeploymentWorkflowInput{
+		wf, err := newDeploymentWorkflow(newDeploymentWorkflowInput{
+			serviceName:          input.serviceName,
 			namePrefix:           namePrefix,
 			checkoutCommitBranch: input.checkoutCommitBranch,
 			destination:          dest,
 			destinationGroup:     input.group.Name,
+			verification:         input.group.Verification,
+			deployWindows:        input.group.DeployWindows,
 			apps:                 input.apps,
 		})
+		if err != nil {
+			return gocto.Workflow{}, nil, err
+		}
 		destinationFriendlyName := v1alpha1.CoalesceSanitizeDestination(dest)
-		groupWf.Jobs[destinationFriendlyName] = newDeployGroupJob(destinationFriendlyName, wf)
+		job := newDeployGroupJob(destinationFriendlyName, wf)
+		job.Permissions = deployPermissions(dest)
+		job.With = map[string]string{
+			constants.WorkflowInputRevision: revisionExpression,
+		}
+		groupWf.Jobs[destinationFriendlyName] = job
 		subWorkflows = append(subWorkflows, wf)
 	}
 
-	return groupWf, subWorkflows
+	if err := orderBatches(groupWf.Jobs, input.group); err != nil {
+		return gocto.Workflow{}, nil, err
+	}
+
+	// every destination deploys the same revision, so any of the first batch, which is never halted, may report it
+	firstBatch := input.group.Batches()[0]
+	groupWf.On.Call.Outputs = revisionCallOutputs(v1alpha1.CoalesceSanitizeDestination(firstBatch[len(firstBatch)-1]))
+
+	return groupWf, subWorkflows, nil
 }
 
 type newDeploymentWorkflowInput struct {
+	serviceName          string
 	namePrefix           string
 	checkoutCommitBranch string
 	destination          v1alpha1.Destination
 	destinationGroup     string
+	verification         *v1alpha1.Verification
+	deployWindows        *v1alpha1.DeployWindows
 	apps                 argocd.ApplicationRepository
 }
 
-func newDeploymentWorkflow(input newDeploymentWorkflowInput) gocto.Workflow {
+func newDeploymentWorkflow(input newDeploymentWorkflowInput) (gocto.Workflow, error) {
 	destinationFriendlyName := v1alpha1.CoalesceSanitizeDestination(input.destination)
 
 	jobName := destinationFriendlyName
 
-	appFilePath, _, ok := input.apps.GetByDestination(input.destination)
-	if !ok {
-		godump.Dump(input.apps)
-		panic(fmt.Errorf("no app found for destination %+v", input.destination))
+	appFilePath, _, err := input.apps.GetByDestination(input.serviceName, input.destinationGroup, input.destination)
+	if err != nil {
+		return gocto.Workflow{}, fmt.Errorf("destination: %s: %w", destinationFriendlyName, err)
 	}
 
 	input.destination.ArgoCD.ApplicationFilePath = util.CoalesceStrings(
@@ -122,23 +172,49 @@ func newDeploymentWorkflow(input newDeploymentWorkflowInput) gocto.Workflow {
 		checkoutCommitBranch: input.checkoutCommitBranch,
 		argoCDSpec:           input.destination.ArgoCD,
 		destinationGroup:     input.destinationGroup,
+		verification:         input.verification,
 	})
 
+	generatedJobs := map[string]gocto.Job{
+		jobName: job,
+	}
+
+	if input.deployWindows != nil {
+		if jobName == constants.DeployWindowJobName {
+			return gocto.Workflow{}, fmt.Errorf("destination name is reserved: %s", jobName)
+		}
+
+		job.Needs = append(job.Needs, constants.DeployWindowJobName)
+		job.If = fmt.Sprintf("needs.%s.outputs.%s == 'true'", constants.DeployWindowJobName, deployWindowOutputOpen)
+		generatedJobs[jobName] = job
+		generatedJobs[constants.DeployWindowJobName] = newDeployWindowJob(*input.deployWindows)
+	}
+
 	jobs := util.MergeMapsShallow(
 		input.destination.Github.ExtraDeployJobs,
-		map[string]gocto.Job{
-			jobName: job,
-		},
+		generatedJobs,
 	)
 
+	on := newRevisionWorkflowOn()
+	on.Call.Outputs = revisionCallOutputs(jobName)
+	if input.deployWindows != nil {
+		on.Dispatch.Inputs[constants.WorkflowInputBypassDeployWindow] = gocto.OnDispatchInput{
+			Description: "promote outside of the deploy windows",
+			Type:        gocto.OnDispatchInputTypeBoolean,
+		}
+		on.Call.Inputs[constants.WorkflowInputBypassDeployWindow] = gocto.CallInput{
+			Description: "promote outside of the deploy windows",
+			Type:        gocto.CallInputTypeBoolean,
+		}
+	}
+
 	wf := gocto.Workflow{
 		Name: input.namePrefix + "-" + destinationFriendlyName,
-		On: gocto.WorkflowOn{
-			Dispatch: &gocto.OnDispatch{},
-			Call:     &gocto.OnCall{},
-		},
+		On:   on,
+		// concurrency groups are shared by every workflow of the repository, so they are scoped to the service,
+		// as GitHub cancels the pending runs of a group, keeping only the latest
 		Concurrency: gocto.Concurrency{
-			Group:            destinationFriendlyName,
+			Group:            util.Join("-", input.serviceName, input.destinationGroup, destinationFriendlyName, input.destination.Namespace),
 			CancelInProgress: false,
 		},
 		Defaults: gocto.Defaults{
@@ -152,5 +228,53 @@ func newDeploymentWorkflow(input newDeploymentWorkflowInput) gocto.Workflow {
 
 	wf = SetWorkflowFilenameWithAlveusPrefix(wf)
 
-	return wf
+	return wf, nil
+}
+
+// revisionExpression refers to the pinned revision from within a called (or dispatched) workflow.
+const revisionExpression = "${{ inputs." + constants.WorkflowInputRevision + " }}"
+
+// newRevisionWorkflowOn is the trigger of the group and destination workflows,
+// which always deploy an explicit revision, whether called by the parent workflow or dispatched by an operator.
+func newRevisionWorkflowOn() gocto.WorkflowOn {
+	return gocto.WorkflowOn{
+		Dispatch: withRevisionDispatchInput(nil, "commit to promote", true),
+		Call: &gocto.OnCall{
+			Inputs: map[string]gocto.CallInput{
+				constants.WorkflowInputRevision: {
+					Description: "commit to promote",
+					Required:    true,
+					Type:        gocto.CallInputTypeString,
+				},
+			},
+		},
+	}
+}
+
+func revisionCallOutputs(jobName string) map[string]gocto.CallOutput {
+	return map[string]gocto.CallOutput{
+		constants.WorkflowInputRevision: {
+			Description: "the promoted commit",
+			Value:       fmt.Sprintf("${{ jobs.%s.outputs.%s }}", jobName, constants.WorkflowInputRevision),
+		},
+	}
+}
+
+// withRevisionDispatchInput returns a copy of dispatch with the revision input added.
+func withRevisionDispatchInput(dispatch *gocto.OnDispatch, description string, required bool) *gocto.OnDispatch {
+	result := &gocto.OnDispatch{
+		Inputs: make(map[string]gocto.OnDispatchInput),
+	}
+
+	if dispatch != nil {
+		maps.Copy(result.Inputs, dispatch.Inputs)
+	}
+
+	result.Inputs[constants.WorkflowInputRevision] = gocto.OnDispatchInput{
+		Description: description,
+		Required:    required,
+		Type:        gocto.OnDispatchInputTypeString,
+	}
+
+	return result
 }
@@ -15,6 +15,33 @@ func MergeMapsShallow[K comparable, T any](vals ...map[K]T) map[K]T {
 	return result
 }
 
+// MergeMapsDeep merges maps from left to right, recursing into nested maps,
+// so later values take precedence. The inputs are not modified.
+func MergeMapsDeep(vals ...map[string]any) map[string]any {
+	var result map[string]any
+	for _, val := range vals {
+		if len(val) == 0 {
+			continue
+		}
+
+		if result == nil {
+			result = make(map[string]any)
+		}
+
+		for k, v := range val {
+			existing, existingIsMap := result[k].(map[string]any)
+			incoming, incomingIsMap := v.(map[string]any)
+			if existingIsMap && incomingIsMap {
+				result[k] = MergeMapsDeep(existing, incoming)
+			} else {
+				result[k] = v
+			}
+		}
+	}
+
+	return result
+}
+
 func CoalesceSlices[T any](vals ...[]T) []T {
 	for _, val := range vals {
 		if len(val) > 0 {
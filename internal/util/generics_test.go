@@ -81,3 +81,33 @@ var _ = Describe("CoalesceSlices", func() {
 		})
 	})
 })
+
+var _ = Describe("MergeMapsDeep", func() {
+	It("should merge nested maps, preferring later values", func() {
+		a := map[string]any{
+			"x": 1,
+			"nested": map[string]any{
+				"y": 1,
+				"z": 1,
+			},
+		}
+		b := map[string]any{
+			"nested": map[string]any{
+				"z": 2,
+			},
+		}
+
+		Expect(MergeMapsDeep(a, b)).To(Equal(map[string]any{
+			"x": 1,
+			"nested": map[string]any{
+				"y": 1,
+				"z": 2,
+			},
+		}))
+		Expect(a["nested"]).To(HaveKeyWithValue("z", 1))
+	})
+
+	It("should return nil when there is nothing to merge", func() {
+		Expect(MergeMapsDeep(nil, map[string]any{})).To(BeNil())
+	})
+})
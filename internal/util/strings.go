@@ -17,6 +17,14 @@ func SprintfDedent(format string, a ...any) string {
 	return val
 }
 
+// Dedent is SprintfDedent, for text which is not a format, e.g. a script using %.
+func Dedent(text string) string {
+	val := strings.Replace(text, "\t", "  ", -1)
+	val = dedent.Dedent(val)
+	val = strings.TrimSpace(val)
+	return val
+}
+
 func Join(sep string, vals ...string) string {
 	return strings.Join(vals, sep)
 }